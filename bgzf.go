@@ -0,0 +1,184 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"github.com/mdom/dtgrep/retime"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// gziBlock is the start of a block of a bgzip file in the compressed file
+// and in the uncompressed data.
+type gziBlock struct {
+	compressed, uncompressed int64
+}
+
+// readGzi reads the blocks of a bgzip file from its .gzi index, written
+// by bgzip -i. The index holds the number of entries followed by the
+// offsets of all blocks but the first as little endian integers.
+func readGzi(filename string) ([]gziBlock, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8 {
+		return nil, errors.New("truncated index " + filename)
+	}
+	n := binary.LittleEndian.Uint64(data)
+	if uint64(len(data)-8)/16 != n || uint64(len(data)-8)%16 != 0 {
+		return nil, errors.New("malformed index " + filename)
+	}
+	blocks := []gziBlock{{0, 0}}
+	for k := uint64(0); k < n; k++ {
+		entry := data[8+16*k:]
+		blocks = append(blocks, gziBlock{
+			compressed:   int64(binary.LittleEndian.Uint64(entry)),
+			uncompressed: int64(binary.LittleEndian.Uint64(entry[8:])),
+		})
+	}
+	return blocks, nil
+}
+
+// openBlock returns the uncompressed data of f from the start of block
+// to the end of the file, as gzip.Reader reads on through the following
+// blocks.
+func openBlock(f *os.File, block gziBlock) (io.Reader, error) {
+	if _, err := f.Seek(block.compressed, os.SEEK_SET); err != nil {
+		return nil, err
+	}
+	return gzip.NewReader(f)
+}
+
+// probeBlock returns the date of the first dated line starting in block.
+func probeBlock(f *os.File, block gziBlock, options Options, format retime.Format) (time.Time, error) {
+	r, err := openBlock(f, block)
+	if err != nil {
+		return time.Time{}, err
+	}
+	scanner := newScanner(r)
+
+	if block.uncompressed > 0 {
+		_, err := readline(scanner) // skip partial line
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	for {
+		line, err := readline(scanner)
+		if err != nil {
+			return time.Time{}, err
+		}
+		dt, err := extract(line, options, format)
+		if err != nil && options.passes(line, err) {
+			continue
+		}
+		if err != nil {
+			abortOnDateError(f.Name(), 0, err, line)
+		}
+		return dt, nil
+	}
+}
+
+// findStartBgzip bisects the blocks of a bgzip file like
+// findStartSeekable bisects plain files and returns an iterator starting
+// at the last block whose first line is before --from.
+func findStartBgzip(f *os.File, blocks []gziBlock, options Options, format retime.Format) (*Iterator, error) {
+	if options.prefilter {
+		outside, err := outsideBgzip(f, blocks, options, format)
+		if err != nil {
+			return nil, err
+		}
+		if outside {
+			return nil, io.EOF
+		}
+	}
+
+	min, max := 0, len(blocks)
+	for max-min > 1 {
+		mid := (max + min) / 2
+		dt, err := probeBlock(f, blocks[mid], options, format)
+		switch {
+		case err == io.EOF:
+			// no dated line after the start of this block
+			max = mid
+		case err != nil:
+			return nil, err
+		case dt.Before(options.from):
+			min = mid
+		default:
+			max = mid
+		}
+	}
+
+	r, err := openBlock(f, blocks[min])
+	if err != nil {
+		return nil, err
+	}
+	i := newIterator(f.Name(), r, blocks[min].uncompressed)
+	if blocks[min].uncompressed > 0 {
+		_, err := i.readline() // skip partial line
+		if err != nil {
+			return nil, err
+		}
+	}
+	return i, nil
+}
+
+// outsideBgzip is like outsideRange for bgzip files. It only decompresses
+// the first block and the last blocks.
+func outsideBgzip(f *os.File, blocks []gziBlock, options Options, format retime.Format) (bool, error) {
+	r, err := openBlock(f, blocks[0])
+	if err != nil {
+		return false, err
+	}
+	first, err := firstDate(r, options, format)
+	if err != nil || first.IsZero() {
+		return false, err
+	}
+	last, err := lastDateBgzip(f, blocks, options, format)
+	if err != nil {
+		return false, err
+	}
+	return outside(first, last, options), nil
+}
+
+// lastDateBgzip returns the date of the last dated line of a bgzip file.
+// Starting with the last block, blocks are read to the end of the file
+// until one has a dated line.
+func lastDateBgzip(f *os.File, blocks []gziBlock, options Options, format retime.Format) (time.Time, error) {
+	for k := len(blocks) - 1; k >= 0; k-- {
+		r, err := openBlock(f, blocks[k])
+		if err != nil {
+			return time.Time{}, err
+		}
+		scanner := newScanner(r)
+		if blocks[k].uncompressed > 0 {
+			_, err := readline(scanner) // skip partial line
+			if err != nil && err != io.EOF {
+				return time.Time{}, err
+			}
+		}
+		var last time.Time
+		for {
+			line, err := readline(scanner)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return time.Time{}, err
+			}
+			if dt, err := extract(line, options, format); err == nil {
+				last = dt
+			}
+		}
+		if !last.IsZero() {
+			return last, nil
+		}
+	}
+	return time.Time{}, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeBgzip writes data to filename as gzip members of blockSize bytes
+// each, cutting lines in between, and the matching .gzi index.
+func writeBgzip(t *testing.T, filename string, data []byte, blockSize int) {
+	var compressed, index bytes.Buffer
+	var n uint64
+	for start := 0; start < len(data); start += blockSize {
+		if start > 0 {
+			binary.Write(&index, binary.LittleEndian, uint64(compressed.Len()))
+			binary.Write(&index, binary.LittleEndian, uint64(start))
+			n++
+		}
+		end := start + blockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		w := gzip.NewWriter(&compressed)
+		w.Write(data[start:end])
+		w.Close()
+	}
+	if err := ioutil.WriteFile(filename, compressed.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var gzi bytes.Buffer
+	binary.Write(&gzi, binary.LittleEndian, n)
+	gzi.Write(index.Bytes())
+	if err := ioutil.WriteFile(filename+".gzi", gzi.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindStartBgzip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dtgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var data bytes.Buffer
+	for k := 0; k < 1000; k++ {
+		fmt.Fprintf(&data, "2010-05-01T00:%02d:%02dZ line %d\n", k/60, k%60, k)
+	}
+	filename := filepath.Join(dir, "input.gz")
+	writeBgzip(t, filename, data.Bytes(), 1000)
+
+	blocks, err := readGzi(filename + ".gzi")
+	if err != nil || len(blocks) != (data.Len()+999)/1000 {
+		t.Fatalf("readGzi returned %d blocks, %v", len(blocks), err)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	format := newFormat("rfc3339", "", 69, false)
+
+	for _, k := range []int{0, 1, 29, 500, 999} {
+		from := time.Date(2010, 5, 1, 0, k/60, k%60, 0, time.UTC)
+		options := Options{from: from, to: future}
+		i, err := findStartBgzip(f, blocks, options, format)
+		if err != nil {
+			t.Errorf("findStartBgzip for line %d failed: %v", k, err)
+			continue
+		}
+		i.Scan(options, format)
+		want := fmt.Sprintf("2010-05-01T00:%02d:%02dZ line %d", k/60, k%60, k)
+		if i.Err != nil || i.Line != want {
+			t.Errorf("findStartBgzip for line %d started at %q, %v", k, i.Line, i.Err)
+		}
+		if offset := int64(bytes.Index(data.Bytes(), []byte(want))); i.Offset != offset {
+			t.Errorf("Offset of line %d is %d, want %d", k, i.Offset, offset)
+		}
+	}
+
+	first := time.Date(2010, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, options := range []Options{
+		{from: first.Add(1000 * time.Second), to: future, prefilter: true},
+		{from: epoch, to: first, prefilter: true},
+	} {
+		if _, err := findStartBgzip(f, blocks, options, format); err != io.EOF {
+			t.Errorf("findStartBgzip from %v to %v returned %v", options.from, options.to, err)
+		}
+	}
+
+	ioutil.WriteFile(filename+".gzi", []byte{1, 0, 0, 0, 0, 0, 0, 0}, 0644)
+	if _, err := readGzi(filename + ".gzi"); err == nil {
+		t.Error("readGzi of a truncated index succeeded")
+	}
+}
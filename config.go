@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultConfig returns the path of the configuration file read if
+// --config isn't given.
+func defaultConfig() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "dtgrep", "config")
+}
+
+// findConfig returns the value of --config in args before they are
+// parsed by flags, or "" if it isn't given.
+func findConfig(flags *flag.FlagSet, args []string) string {
+	for n := 0; n < len(args); n++ {
+		arg := args[n]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if strings.HasPrefix(name, "config=") {
+			return strings.TrimPrefix(name, "config=")
+		}
+		if name == "config" {
+			if n+1 < len(args) {
+				return args[n+1]
+			}
+			break
+		}
+		// Skip the value of options given as separate argument.
+		f := flags.Lookup(name)
+		if f == nil {
+			continue
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			n++
+		}
+	}
+	return ""
+}
+
+// loadConfig sets the flags in the configuration file path. Each line is
+// an option and its value like "format = rfc3339", boolean options may
+// be given without value. Empty lines and lines starting with # are
+// ignored.
+func loadConfig(flags *flag.FlagSet, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		value := "true"
+		if len(parts) == 2 {
+			value = strings.TrimSpace(parts[1])
+		}
+		where := path + " line " + strconv.Itoa(n) + ": "
+		f := flags.Lookup(name)
+		if f == nil || name == "config" {
+			return errors.New(where + "unknown option " + name)
+		}
+		if err := flags.Set(name, value); err != nil {
+			return errors.New(where + "invalid value for " + name + ": " + err.Error())
+		}
+		f.DefValue = value
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFindConfig(t *testing.T) {
+	tests := []struct {
+		args   []string
+		config string
+	}{
+		{[]string{"--config", "a", "input"}, "a"},
+		{[]string{"--format", "rfc3339", "-config=b"}, "b"},
+		{[]string{"--format", "rfc3339", "input", "--config", "c"}, ""},
+		{[]string{"--", "--config", "d"}, ""},
+		{[]string{"--config"}, ""},
+		{[]string{"--skip-dateless", "--config", "e"}, "e"},
+	}
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("format", "rsyslog", "")
+	flags.Bool("skip-dateless", false, "")
+	for _, v := range tests {
+		if config := findConfig(flags, v.args); config != v.config {
+			t.Errorf("findConfig(%v) returned %q, expected %q", v.args, config, v.config)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "dtgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	newFlags := func() (*flag.FlagSet, *string, *bool) {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		format := flags.String("format", "rsyslog", "")
+		skip := flags.Bool("skip-dateless", false, "")
+		return flags, format, skip
+	}
+
+	ioutil.WriteFile(f.Name(), []byte("# defaults\n\nformat = rfc3339\n  skip-dateless\n"), 0644)
+	flags, format, skip := newFlags()
+	if err := loadConfig(flags, f.Name()); err != nil {
+		t.Fatal("loadConfig returned", err)
+	}
+	if *format != "rfc3339" || !*skip {
+		t.Errorf("loadConfig set format %q and skip-dateless %v", *format, *skip)
+	}
+	flags.Parse([]string{"--format", "apache"})
+	if result := []string{*format, flags.Lookup("format").DefValue}; !reflect.DeepEqual(result, []string{"apache", "rfc3339"}) {
+		t.Errorf("Arguments after loadConfig set format and default to %v", result)
+	}
+
+	for _, content := range []string{"colour = always\n", "skip-dateless = maybe\n", "config = other\n"} {
+		ioutil.WriteFile(f.Name(), []byte(content), 0644)
+		flags, _, _ := newFlags()
+		flags.String("config", "", "")
+		if err := loadConfig(flags, f.Name()); err == nil {
+			t.Errorf("loadConfig with %q returned no error", content)
+		}
+	}
+}
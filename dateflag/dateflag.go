@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/mdom/dtgrep/fixtime"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,6 +16,7 @@ type formats struct {
 
 type DateFlag struct {
 	date, Now time.Time
+	spec      string
 }
 
 func (d *DateFlag) String() string {
@@ -46,6 +48,8 @@ func (d *DateFlag) Set(dateSpec string) error {
 		}
 	}
 
+	d.spec = dateSpec
+
 	var modifiers []func(time.Time) time.Time
 	fields := strings.Fields(modPart)
 
@@ -73,6 +77,21 @@ func (d *DateFlag) Set(dateSpec string) error {
 
 	if datePart == "now" || datePart == "" {
 		dt = d.Now
+	} else if strings.HasPrefix(datePart, "@") {
+		seconds, err := strconv.ParseInt(datePart[1:], 10, 64)
+		if err != nil {
+			return errors.New("Invalid epoch seconds " + datePart)
+		}
+		dt = time.Unix(seconds, 0).In(time.Local)
+	} else if datePart == "yesterday" {
+		year, month, day := d.Now.Date()
+		dt = time.Date(year, month, day-1, 0, 0, 0, 0, d.Now.Location())
+	} else if offset, ok := relativeOffset(datePart); ok {
+		duration, err := parseRelativeDuration(offset)
+		if err != nil {
+			return errors.New("Invalid relative date " + datePart + ": " + err.Error())
+		}
+		dt = d.Now.Add(-duration)
 	} else {
 		specs := []formats{
 			{"04", fixtime.AddDateHour},
@@ -103,3 +122,72 @@ func (d *DateFlag) Set(dateSpec string) error {
 	d.date = dt
 	return nil
 }
+
+// relativeOffset returns the duration of relative dates like "2h ago" or
+// "-2h" and whether datePart is relative at all.
+func relativeOffset(datePart string) (string, bool) {
+	if strings.HasSuffix(datePart, " ago") {
+		return strings.TrimSpace(strings.TrimSuffix(datePart, " ago")), true
+	}
+	if strings.HasPrefix(datePart, "-") {
+		return datePart[1:], true
+	}
+	return "", false
+}
+
+var dayDuration = regexp.MustCompile(`^(\d+)([dw])$`)
+
+// parseRelativeDuration parses durations of the time package and whole
+// days and weeks like "1d" or "2w", which are 24 hours and 7 days long.
+func parseRelativeDuration(spec string) (time.Duration, error) {
+	if match := dayDuration.FindStringSubmatch(spec); match != nil {
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return 0, err
+		}
+		if match[2] == "w" {
+			n *= 7
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	duration, err := time.ParseDuration(spec)
+	if err != nil {
+		return 0, err
+	}
+	if duration < 0 {
+		return 0, errors.New("negative duration " + spec)
+	}
+	return duration, nil
+}
+
+// SetNow sets Now and evaluates the last datespec again, so "now" and
+// incomplete dates are relative to the new time.
+func (d *DateFlag) SetNow(now time.Time) error {
+	d.Now = now
+	if d.spec == "" {
+		return nil
+	}
+	return d.Set(d.spec)
+}
+
+// SetParts sets the date from a separate date like "2006-01-02" and clock
+// time like "15:04" or "15:04:05". A missing clock time defaults to
+// midnight, a missing date to the day of Now.
+func (d *DateFlag) SetParts(date, clock string) error {
+	if date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return err
+		}
+	}
+	if clock == "" {
+		clock = "00:00"
+	} else if _, err := time.Parse("15:04", clock); err != nil {
+		if _, err := time.Parse("15:04:05", clock); err != nil {
+			return err
+		}
+	}
+	if date == "" {
+		return d.Set(clock)
+	}
+	return d.Set(date + " " + clock)
+}
@@ -68,3 +68,95 @@ func TestDateFlagSet(t *testing.T) {
 	}
 
 }
+
+func TestDateFlagSetRelative(t *testing.T) {
+	time.Local = time.UTC
+	now, _ := time.Parse(time.RFC3339, "2016-05-09T10:40:00Z")
+
+	tests := []struct {
+		spec, want string
+	}{
+		{"2h ago", "2016-05-09 08:40:00 +0000 UTC"},
+		{"30m ago", "2016-05-09 10:10:00 +0000 UTC"},
+		{"1d ago", "2016-05-08 10:40:00 +0000 UTC"},
+		{"-1d", "2016-05-08 10:40:00 +0000 UTC"},
+		{"-1w", "2016-05-02 10:40:00 +0000 UTC"},
+		{"-90s", "2016-05-09 10:38:30 +0000 UTC"},
+		{"yesterday", "2016-05-08 00:00:00 +0000 UTC"},
+		{"2h ago truncate 1h", "2016-05-09 08:00:00 +0000 UTC"},
+		{"yesterday add 12h", "2016-05-08 12:00:00 +0000 UTC"},
+		{"@1462790400", "2016-05-09 10:40:00 +0000 UTC"},
+		{"@0 add 1h", "1970-01-01 01:00:00 +0000 UTC"},
+	}
+	for _, test := range tests {
+		d := &DateFlag{Now: now}
+		if err := d.Set(test.spec); err != nil {
+			t.Errorf("Set(%q) failed: %v", test.spec, err)
+			continue
+		}
+		if d.String() != test.want {
+			t.Errorf("Set(%q) = %s, want %s", test.spec, d.String(), test.want)
+		}
+	}
+
+	for _, spec := range []string{"2x ago", "ago", "-", "-2h ago", "-1.5d", "@", "@1.5", "@now"} {
+		d := &DateFlag{Now: now}
+		if err := d.Set(spec); err == nil {
+			t.Errorf("Set(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestDateFlagSetNow(t *testing.T) {
+	time.Local = time.UTC
+	now, _ := time.Parse(time.RFC3339, "2016-05-09T10:40:00Z")
+	d := &DateFlag{Now: now}
+
+	err := d.SetNow(now)
+	if err != nil || !d.Get().IsZero() {
+		t.Error("SetNow without datespec set a date")
+	}
+
+	d.Set("12:15 add 5m")
+	now, _ = time.Parse(time.RFC3339, "2010-01-02T10:40:00Z")
+	err = d.SetNow(now)
+	if err != nil || d.String() != "2010-01-02 12:20:00 +0000 UTC" {
+		t.Error("SetNow didn't evaluate 12:15 add 5m again")
+	}
+
+	d.Set("now truncate 1h")
+	now, _ = time.Parse(time.RFC3339, "2011-01-02T10:40:00Z")
+	err = d.SetNow(now)
+	if err != nil || d.String() != "2011-01-02 10:00:00 +0000 UTC" {
+		t.Error("SetNow didn't evaluate now truncate 1h again")
+	}
+}
+
+func TestDateFlagSetParts(t *testing.T) {
+	time.Local = time.UTC
+	now, _ := time.Parse(time.RFC3339, "2016-05-09T10:40:00Z")
+
+	tests := []struct {
+		date, clock string
+		result      string
+	}{
+		{"2016-01-02", "15:04", "2016-01-02 15:04:00 +0000 UTC"},
+		{"2016-01-02", "15:04:05", "2016-01-02 15:04:05 +0000 UTC"},
+		{"2016-01-02", "", "2016-01-02 00:00:00 +0000 UTC"},
+		{"", "15:04", "2016-05-09 15:04:00 +0000 UTC"},
+	}
+	for _, v := range tests {
+		d := &DateFlag{Now: now}
+		err := d.SetParts(v.date, v.clock)
+		if err != nil || d.String() != v.result {
+			t.Errorf("Passing %q and %q returned %v, %v", v.date, v.clock, d, err)
+		}
+	}
+
+	for _, v := range [][2]string{{"2016-01-32", ""}, {"12:00", ""}, {"", "15"}, {"", "2016-01-02"}} {
+		d := &DateFlag{Now: now}
+		if err := d.SetParts(v[0], v[1]); err == nil {
+			t.Errorf("Passing %q and %q succeeded", v[0], v[1])
+		}
+	}
+}
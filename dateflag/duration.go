@@ -0,0 +1,71 @@
+package dateflag
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DurationFlag is a duration given either in the syntax of the time
+// package like "1h30m" or as ISO 8601 duration like "PT1H30M". Days and
+// weeks are 24 hours and 7 days long. Months and years are rejected as
+// their length depends on the date.
+type DurationFlag struct {
+	duration time.Duration
+	spec     string
+}
+
+func (d *DurationFlag) String() string {
+	return d.spec
+}
+
+func (d *DurationFlag) Get() time.Duration {
+	return d.duration
+}
+
+func (d *DurationFlag) Set(spec string) error {
+	duration, err := time.ParseDuration(spec)
+	if err != nil {
+		duration, err = parseISODuration(spec)
+	}
+	if err != nil {
+		return err
+	}
+	d.duration, d.spec = duration, spec
+	return nil
+}
+
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?` +
+	`(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+
+func parseISODuration(spec string) (time.Duration, error) {
+	match := isoDuration.FindStringSubmatch(spec)
+	if match == nil || spec == "P" || spec[len(spec)-1] == 'T' {
+		return 0, errors.New("Can't parse duration " + spec)
+	}
+	if match[1] != "" || match[2] != "" {
+		return 0, errors.New("Months and years are not supported in duration " + spec)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute}
+	var duration time.Duration
+	for k, unit := range units {
+		if match[k+3] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(match[k+3], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		duration += time.Duration(n) * unit
+	}
+	if match[7] != "" {
+		sec, err := strconv.ParseFloat(strings.Replace(match[7], ",", ".", 1), 64)
+		if err != nil {
+			return 0, err
+		}
+		duration += time.Duration(sec * float64(time.Second))
+	}
+	return duration, nil
+}
@@ -0,0 +1,34 @@
+package dateflag
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationFlag(t *testing.T) {
+	tests := []struct {
+		spec     string
+		duration time.Duration
+	}{
+		{"1h30m", 90 * time.Minute},
+		{"PT1H30M", 90 * time.Minute},
+		{"P1DT2H", 26 * time.Hour},
+		{"P2W", 14 * 24 * time.Hour},
+		{"PT0.5S", 500 * time.Millisecond},
+		{"PT1,5S", 1500 * time.Millisecond},
+		{"P1D", 24 * time.Hour},
+	}
+	for _, v := range tests {
+		d := &DurationFlag{}
+		if err := d.Set(v.spec); err != nil || d.Get() != v.duration {
+			t.Errorf("Passing %s returned %v, %v", v.spec, d.Get(), err)
+		}
+	}
+
+	for _, v := range []string{"P", "PT", "P1M", "P1Y", "P1DT", "PT1D", "1 hour"} {
+		d := &DurationFlag{}
+		if err := d.Set(v); err == nil {
+			t.Error("Passing", v, "succeeded")
+		}
+	}
+}
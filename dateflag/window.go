@@ -0,0 +1,74 @@
+package dateflag
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// WindowFlag is a daily recurring clock window like "09:00-17:00". The
+// start is inclusive, the end exclusive. If the end is before the start,
+// the window spans midnight.
+type WindowFlag struct {
+	start, end time.Duration
+	spec       string
+}
+
+func (w *WindowFlag) String() string {
+	return w.spec
+}
+
+func (w *WindowFlag) Set(spec string) error {
+	parts := strings.Split(spec, "-")
+	if len(parts) != 2 {
+		return errors.New("Window must be of the form START-END")
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return errors.New("Window start and end must differ")
+	}
+	w.start, w.end, w.spec = start, end, spec
+	return nil
+}
+
+// IsSet reports whether a window was given.
+func (w *WindowFlag) IsSet() bool {
+	return w.spec != ""
+}
+
+// Contains reports whether the wall clock time of dt lies within the
+// window. An unset window contains every time.
+func (w *WindowFlag) Contains(dt time.Time) bool {
+	if !w.IsSet() {
+		return true
+	}
+	d := sinceMidnight(dt)
+	if w.start < w.end {
+		return d >= w.start && d < w.end
+	}
+	return d >= w.start || d < w.end
+}
+
+func parseClock(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return sinceMidnight(t), nil
+		}
+	}
+	return 0, errors.New("Can't parse clock time " + s)
+}
+
+func sinceMidnight(dt time.Time) time.Duration {
+	hour, min, sec := dt.Clock()
+	return time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute +
+		time.Duration(sec)*time.Second + time.Duration(dt.Nanosecond())
+}
@@ -0,0 +1,51 @@
+package dateflag
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowFlag(t *testing.T) {
+	w := &WindowFlag{}
+
+	if !w.Contains(time.Now()) {
+		t.Error("Unset window should contain every time")
+	}
+
+	if err := w.Set("09:00-17:00"); err != nil {
+		t.Fatal("Passing 09:00-17:00 failed:", err)
+	}
+
+	tests := []struct {
+		date     string
+		contains bool
+	}{
+		{"2016-05-09T08:59:59Z", false},
+		{"2016-05-09T09:00:00Z", true},
+		{"2016-05-09T12:30:00Z", true},
+		{"2016-05-09T16:59:59Z", true},
+		{"2016-05-09T17:00:00Z", false},
+	}
+	for _, v := range tests {
+		dt, _ := time.Parse(time.RFC3339, v.date)
+		if w.Contains(dt) != v.contains {
+			t.Error("Window 09:00-17:00 failed for", v.date)
+		}
+	}
+
+	if err := w.Set("22:00-06:00:30"); err != nil {
+		t.Fatal("Passing 22:00-06:00:30 failed:", err)
+	}
+	for _, v := range []string{"2016-05-09T23:00:00Z", "2016-05-09T06:00:29Z"} {
+		dt, _ := time.Parse(time.RFC3339, v)
+		if !w.Contains(dt) {
+			t.Error("Window over midnight does not contain", v)
+		}
+	}
+
+	for _, v := range []string{"09:00", "09:00-", "9-17", "09:00-09:00"} {
+		if err := w.Set(v); err == nil {
+			t.Error("Passing", v, "succeeded")
+		}
+	}
+}
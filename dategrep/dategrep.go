@@ -0,0 +1,299 @@
+// Package dategrep finds the lines of sorted logs dated within a range
+// and merges the lines of several logs by date.
+package dategrep
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/mdom/dtgrep/fixtime"
+	"github.com/mdom/dtgrep/retime"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Epoch is before any date found in logs. It's the start of ranges
+// without start.
+var Epoch = time.Unix(math.MinInt64/2, 0)
+
+// ErrDuration is returned by DateRange if a duration is given along
+// with a start and an end.
+var ErrDuration = errors.New("duration can only be used with either from or to")
+
+// DateRange completes the range given by from, to and duration, any of
+// them may be zero. A duration alone ends at now truncated to the hour,
+// minute or second, depending on its length. Without duration, a missing
+// to is now and a missing from is Epoch.
+func DateRange(from, to time.Time, duration time.Duration, now time.Time) (time.Time, time.Time, error) {
+
+	// duration, from and to specified
+	if duration != 0 && !to.IsZero() && !from.IsZero() {
+		return from, to, ErrDuration
+	}
+
+	// only duration specified
+	if duration != 0 && to.IsZero() && from.IsZero() {
+		switch {
+		case duration.Hours() >= 1:
+			to = now.Truncate(time.Duration(1) * time.Hour)
+		case duration.Minutes() >= 1:
+			to = now.Truncate(time.Duration(1) * time.Minute)
+		default:
+			to = now.Truncate(time.Duration(1) * time.Second)
+		}
+		from = to.Add(-duration)
+	}
+
+	if duration != 0 && !to.IsZero() && from.IsZero() {
+		from = to.Add(-duration)
+	}
+
+	if duration != 0 && to.IsZero() && !from.IsZero() {
+		to = from.Add(duration)
+	}
+
+	if to.IsZero() {
+		to = now
+	}
+
+	if from.IsZero() {
+		from = Epoch
+	}
+
+	return from, to, nil
+}
+
+// InRange reports whether dt is between from inclusively and to
+// exclusively.
+func InRange(dt, from, to time.Time) bool {
+	return (dt.Equal(from) || dt.After(from)) && dt.Before(to)
+}
+
+// DateError is returned by Grep for a line without or with a malformed
+// date.
+type DateError struct {
+	// Input is the index of the reader the line was read from.
+	Input int
+	Line  string
+	Err   error
+}
+
+func (e *DateError) Error() string {
+	return fmt.Sprintf("input %d: %v: %s", e.Input, e.Err, e.Line)
+}
+
+func (e *DateError) Unwrap() error {
+	return e.Err
+}
+
+// ReadError is returned by Grep if a reader fails.
+type ReadError struct {
+	// Input is the index of the failed reader.
+	Input int
+	Err   error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("input %d: %v", e.Input, e.Err)
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// ErrTooLong is wrapped by a ReadError for a line longer than
+// Search.BufferSize.
+var ErrTooLong = errors.New("line too long")
+
+// Search holds the settings of Grep.
+type Search struct {
+	// SkipDateless ignores lines without date, Multiline prints them
+	// after the dated line before them. Otherwise they are an error, as
+	// are lines with a malformed date.
+	SkipDateless, Multiline bool
+
+	// BufferSize is the length of the longest line, 1MB by default.
+	BufferSize int
+
+	// Now completes dates of formats without year. It defaults to the
+	// current time.
+	Now time.Time
+}
+
+// Grep writes the lines of readers dated from from inclusively to to
+// exclusively to w, merged by date. Each reader has to be sorted by
+// date, lines of equal dates are written in the order of the readers.
+// Lines without or with a malformed date are an error.
+func Grep(from, to time.Time, readers []io.Reader, format retime.Format, w io.Writer) error {
+	return Search{}.Grep(from, to, readers, format, w)
+}
+
+// Grep is like the function Grep with the settings of s.
+func (s Search) Grep(from, to time.Time, readers []io.Reader, format retime.Format, w io.Writer) error {
+	if s.Now.IsZero() {
+		s.Now = time.Now()
+	}
+	if s.BufferSize == 0 {
+		s.BufferSize = 1024 * 1024
+	}
+	g := grep{Search: s, from: from, to: to, format: format}
+	iterators := make(iterators, 0, len(readers))
+	for n, r := range readers {
+		i := g.newIterator(n, r)
+		if err := g.scan(i); err != nil {
+			return err
+		}
+		iterators = append(iterators, i)
+	}
+	for {
+		iterators = g.filter(iterators)
+		if len(iterators) == 0 {
+			return nil
+		}
+		sort.Sort(iterators)
+
+		until := to
+		if len(iterators) > 1 {
+			until = iterators[1].time
+		}
+		i := iterators[0]
+		if _, err := fmt.Fprintln(w, i.line); err != nil {
+			return err
+		}
+		if err := g.print(i, w, until); err != nil {
+			return err
+		}
+	}
+}
+
+// grep is a single call of Grep.
+type grep struct {
+	Search
+	from, to time.Time
+	format   retime.Format
+}
+
+// iterator reads the lines of a single input.
+type iterator struct {
+	line string
+	time time.Time
+
+	// err is set to io.EOF after the last line in the range was read.
+	err error
+
+	input   int
+	scanner *bufio.Scanner
+}
+
+func (g grep) newIterator(input int, r io.Reader) *iterator {
+	scanner := bufio.NewScanner(r)
+	size := bufio.MaxScanTokenSize
+	if g.BufferSize < size {
+		size = g.BufferSize
+	}
+	scanner.Buffer(make([]byte, size), g.BufferSize)
+	return &iterator{input: input, scanner: scanner}
+}
+
+// iterators are sorted by the date of their current line, equal dates
+// by the order of their inputs.
+type iterators []*iterator
+
+func (it iterators) Len() int      { return len(it) }
+func (it iterators) Swap(i, j int) { it[i], it[j] = it[j], it[i] }
+func (it iterators) Less(i, j int) bool {
+	if it[i].time.Equal(it[j].time) {
+		return it[i].input < it[j].input
+	}
+	return it[i].time.Before(it[j].time)
+}
+
+// filter returns the iterators whose current line is in the range.
+func (g grep) filter(it iterators) iterators {
+	var p iterators
+	for _, i := range it {
+		if i.err == nil && InRange(i.time, g.from, g.to) {
+			p = append(p, i)
+		}
+	}
+	return p
+}
+
+func (g grep) readline(i *iterator) error {
+	if !i.scanner.Scan() {
+		err := i.scanner.Err()
+		if err == bufio.ErrTooLong {
+			err = ErrTooLong
+		}
+		if err != nil {
+			return &ReadError{Input: i.input, Err: err}
+		}
+		return io.EOF
+	}
+	i.line = i.scanner.Text()
+	return nil
+}
+
+func (g grep) extract(i *iterator) (time.Time, error) {
+	dt, err := g.format.Extract(i.line)
+	if err == nil && !g.format.HasYear() {
+		dt = fixtime.AddYear(dt, g.Now)
+	}
+	return dt, err
+}
+
+// scan skips the lines before the range.
+func (g grep) scan(i *iterator) error {
+	for {
+		if err := g.readline(i); err != nil {
+			i.err = io.EOF
+			if err != io.EOF {
+				return err
+			}
+			return nil
+		}
+		i.time, i.err = g.extract(i)
+		if i.err == retime.ErrNoMatch && (g.SkipDateless || g.Multiline) {
+			continue
+		}
+		if i.err != nil {
+			return &DateError{Input: i.input, Line: i.line, Err: i.err}
+		}
+		if !i.time.Before(g.to) {
+			i.err = io.EOF
+			return nil
+		}
+		if !i.time.Before(g.from) {
+			return nil
+		}
+	}
+}
+
+// print writes the lines of i dated before until and returns at the
+// first line after it.
+func (g grep) print(i *iterator, w io.Writer, until time.Time) error {
+	for {
+		if err := g.readline(i); err != nil {
+			i.err = io.EOF
+			if err != io.EOF {
+				return err
+			}
+			return nil
+		}
+		i.time, i.err = g.extract(i)
+		switch {
+		case i.err == retime.ErrNoMatch && g.Multiline:
+		case i.err == retime.ErrNoMatch && g.SkipDateless:
+			continue
+		case i.err != nil:
+			return &DateError{Input: i.input, Line: i.line, Err: i.err}
+		case !i.time.Before(until):
+			return nil
+		}
+		if _, err := fmt.Fprintln(w, i.line); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package dategrep
+
+import (
+	"bytes"
+	"errors"
+	"github.com/mdom/dtgrep/retime"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateRange(t *testing.T) {
+	now := time.Date(2016, 5, 9, 12, 34, 56, 0, time.UTC)
+	from := time.Date(2016, 5, 9, 10, 40, 0, 0, time.UTC)
+	to := time.Date(2016, 5, 9, 11, 40, 0, 0, time.UTC)
+
+	tests := []struct {
+		from, to             time.Time
+		duration             time.Duration
+		resultFrom, resultTo time.Time
+	}{
+		{from, to, 0, from, to},
+		{from, time.Time{}, 20 * time.Second, from, from.Add(20 * time.Second)},
+		{time.Time{}, to, 20 * time.Second, to.Add(-20 * time.Second), to},
+		{time.Time{}, to, 0, Epoch, to},
+		{from, time.Time{}, 0, from, now},
+		{time.Time{}, time.Time{}, 2 * time.Hour, now.Truncate(time.Hour).Add(-2 * time.Hour), now.Truncate(time.Hour)},
+		{time.Time{}, time.Time{}, 30 * time.Second, now.Add(-30 * time.Second), now},
+	}
+	for _, v := range tests {
+		from, to, err := DateRange(v.from, v.to, v.duration, now)
+		if err != nil || !from.Equal(v.resultFrom) || !to.Equal(v.resultTo) {
+			t.Errorf("DateRange(%v, %v, %v) returned %v, %v, %v, expected %v, %v",
+				v.from, v.to, v.duration, from, to, err, v.resultFrom, v.resultTo)
+		}
+	}
+
+	if _, _, err := DateRange(from, to, time.Hour, now); err != ErrDuration {
+		t.Error("DateRange with from, to and duration returned", err)
+	}
+}
+
+func TestGrep(t *testing.T) {
+	format, _ := retime.New(time.RFC3339, time.UTC)
+	input1 := `2010-05-01T00:00:00Z file 1 line 1
+2010-05-01T00:00:02Z file 1 line 2
+continuation
+2010-05-01T00:00:04Z file 1 line 3
+`
+	input2 := `2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:02Z file 2 line 2
+2010-05-01T00:00:05Z file 2 line 3
+`
+	tests := []struct {
+		search   Search
+		from, to time.Time
+		result   string
+	}{
+		{
+			Search{SkipDateless: true},
+			time.Date(2010, 5, 1, 0, 0, 1, 0, time.UTC),
+			time.Date(2010, 5, 1, 0, 0, 5, 0, time.UTC),
+			`2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:02Z file 2 line 2
+2010-05-01T00:00:04Z file 1 line 3
+`,
+		},
+		{
+			Search{Multiline: true},
+			time.Date(2010, 5, 1, 0, 0, 2, 0, time.UTC),
+			time.Date(2010, 5, 1, 0, 0, 3, 0, time.UTC),
+			`2010-05-01T00:00:02Z file 1 line 2
+continuation
+2010-05-01T00:00:02Z file 2 line 2
+`,
+		},
+		{
+			Search{SkipDateless: true},
+			time.Date(2010, 5, 1, 0, 0, 10, 0, time.UTC),
+			time.Date(2010, 5, 1, 0, 0, 20, 0, time.UTC),
+			"",
+		},
+	}
+	for _, v := range tests {
+		var b bytes.Buffer
+		readers := []io.Reader{strings.NewReader(input1), strings.NewReader(input2)}
+		err := v.search.Grep(v.from, v.to, readers, format, &b)
+		if err != nil || b.String() != v.result {
+			t.Errorf("Grep returned %q, %v, expected %q", b.String(), err, v.result)
+		}
+	}
+
+	to := time.Date(2010, 5, 1, 0, 0, 5, 0, time.UTC)
+	readers := []io.Reader{strings.NewReader(input2), strings.NewReader(input1)}
+	err := Grep(Epoch, to, readers, format, &bytes.Buffer{})
+	var dateError *DateError
+	if !errors.As(err, &dateError) || dateError.Input != 1 || dateError.Line != "continuation" || !errors.Is(err, retime.ErrNoMatch) {
+		t.Error("Grep with dateless line returned", err)
+	}
+
+	search := Search{BufferSize: 40, SkipDateless: true}
+	readers = []io.Reader{strings.NewReader(input1), strings.NewReader(input2 + strings.Repeat("x", 50))}
+	err = search.Grep(Epoch, to.Add(time.Hour), readers, format, &bytes.Buffer{})
+	var readError *ReadError
+	if !errors.As(err, &readError) || readError.Input != 1 || !errors.Is(err, ErrTooLong) {
+		t.Error("Grep with long line returned", err)
+	}
+}
@@ -10,10 +10,19 @@ func AddDateHour(dt time.Time, now time.Time) time.Time {
 	return time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), dt.Minute(), dt.Second(), dt.Nanosecond(), dt.Location())
 }
 
+// Skew is how far a date completed by AddYear may lie after now before
+// it's put in the previous year, so lines of clocks running slightly
+// ahead stay in the current year.
+const Skew = 6 * time.Hour
+
+// AddYear puts a date in year zero, as parsed from layouts without year,
+// in the year of now. Dates more than Skew after now are put in the year
+// before, so logs read in January keep their December lines in the last
+// year.
 func AddYear(dt time.Time, now time.Time) time.Time {
 	if dt.Year() == 0 {
 		dt = dt.AddDate(now.Year(), 0, 0)
-		if dt.After(now) {
+		if dt.After(now.Add(Skew)) {
 			dt = dt.AddDate(-1, 0, 0)
 		}
 	}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// followInterval is the time waited for new data at the end of a followed
+// file.
+var followInterval = 500 * time.Millisecond
+
+// followReader reads a file like tail -f: at its end it waits for more
+// data instead of returning io.EOF. A file truncated by log rotation is
+// read again from its start. The checkpoint is saved before waiting, so
+// it's current when dtgrep gets interrupted.
+type followReader struct {
+	file *os.File
+}
+
+func (r followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.file.Read(p)
+		if n > 0 || err != nil && err != io.EOF {
+			return n, err
+		}
+		progress.save()
+		time.Sleep(followInterval)
+		if err := r.rewindTruncated(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// rewindTruncated seeks to the start of the file if it got shorter than
+// the current position.
+func (r followReader) rewindTruncated() error {
+	pos, err := r.file.Seek(0, os.SEEK_CUR)
+	if err != nil {
+		return err
+	}
+	info, err := r.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < pos {
+		_, err = r.file.Seek(0, os.SEEK_SET)
+	}
+	return err
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/mdom/dtgrep/retime"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// seekIndex maps offsets of a file to the date of the first dated line
+// after them. It's saved with --cache-index to skip the binary search on
+// the next run. The index is only valid for the file with the given size
+// and modification time searched with the same format.
+type seekIndex struct {
+	Path    string       `json:"path"`
+	Size    int64        `json:"size"`
+	ModTime time.Time    `json:"mtime"`
+	Format  string       `json:"format"`
+	Entries []indexEntry `json:"entries"`
+}
+
+type indexEntry struct {
+	Offset int64     `json:"offset"`
+	Time   time.Time `json:"time"`
+}
+
+// maxIndexEntries limits the size of an index. Larger files are sampled
+// in larger intervals.
+const maxIndexEntries = 4096
+
+// indexFile returns the name of the index for the file at path in dir.
+func indexFile(dir, path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha1.Sum([]byte(path))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// newIndex returns an empty index for f.
+func newIndex(f *os.File, format retime.Format) (*seekIndex, error) {
+	fileInfo, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	path, err := filepath.Abs(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &seekIndex{
+		Path:    path,
+		Size:    fileInfo.Size(),
+		ModTime: fileInfo.ModTime(),
+		Format:  format.String() + " " + loc.String(),
+	}, nil
+}
+
+// loadIndex returns the index for f in dir. It returns nil if there's no
+// index or it doesn't match f anymore.
+func loadIndex(dir string, f *os.File, format retime.Format) *seekIndex {
+	current, err := newIndex(f, format)
+	if err != nil {
+		return nil
+	}
+	b, err := ioutil.ReadFile(indexFile(dir, f.Name()))
+	if err != nil {
+		return nil
+	}
+	var idx seekIndex
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return nil
+	}
+	if idx.Path != current.Path || idx.Size != current.Size ||
+		!idx.ModTime.Equal(current.ModTime) || idx.Format != current.Format {
+		return nil
+	}
+	return &idx
+}
+
+// buildIndex samples the dates of f at evenly spaced offsets.
+func buildIndex(f *os.File, options Options, format retime.Format) (*seekIndex, error) {
+	idx, err := newIndex(f, format)
+	if err != nil {
+		return nil, err
+	}
+	interval := int64(4096)
+	if n := idx.Size / maxIndexEntries; n > interval {
+		interval = (n/4096 + 1) * 4096
+	}
+	for offset := interval; offset < idx.Size; offset += interval {
+		dt, err := probeDate(f, offset, options, format)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		idx.Entries = append(idx.Entries, indexEntry{Offset: offset, Time: dt})
+	}
+	return idx, nil
+}
+
+// save writes the index to dir.
+func (idx *seekIndex) save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	name := indexFile(dir, idx.Path)
+	if err := ioutil.WriteFile(name+".tmp", b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
+
+// findStartCached is like findStartSeekable, but uses the index of f in
+// dir. The index is built first if it's missing or outdated.
+func findStartCached(dir string, f *os.File, options Options, format retime.Format) (*Iterator, error) {
+	if options.prefilter {
+		outside, err := outsideRange(f, options, format)
+		if err != nil {
+			return nil, err
+		}
+		if outside {
+			return nil, io.EOF
+		}
+	}
+
+	idx := loadIndex(dir, f, format)
+	if idx == nil {
+		var err error
+		idx, err = buildIndex(f, options, format)
+		if err != nil {
+			return nil, err
+		}
+		if err := idx.save(dir); err != nil {
+			log.Println("Warning: Can't save index:", err)
+		}
+	}
+	return findStartIndexed(f, idx, options)
+}
+
+// findStartIndexed returns an iterator starting at the last indexed
+// offset before options.from.
+func findStartIndexed(f *os.File, idx *seekIndex, options Options) (*Iterator, error) {
+	var start int64
+	for _, e := range idx.Entries {
+		if !e.Time.Before(options.from) {
+			break
+		}
+		start = e.Offset
+	}
+	if _, err := f.Seek(start, os.SEEK_SET); err != nil {
+		return nil, err
+	}
+	i := newIterator(f.Name(), f, start)
+	if start > 0 {
+		_, err := i.readline() // skip partial line
+		if err != nil {
+			return nil, err
+		}
+	}
+	return i, nil
+}
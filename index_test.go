@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mdom/dtgrep/retime"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dtgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "input")
+	file, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	for i := 0; i < 600; i++ {
+		fmt.Fprintf(file, "2010-05-01T00:%02d:%02dZ line %d\n", i/60, i%60, i)
+	}
+
+	format, _ := retime.New(time.RFC3339, time.UTC)
+	var options Options
+	options.from, _ = time.Parse(time.RFC3339, "2010-05-01T00:05:00Z")
+	options.to, _ = time.Parse(time.RFC3339, "2010-05-01T00:06:00Z")
+
+	if loadIndex(dir, file, format) != nil {
+		t.Error("Loaded index before it was built")
+	}
+
+	idx, err := buildIndex(file, options, format)
+	if err != nil {
+		t.Fatal("Can't build index:", err)
+	}
+	// Lines are 30 to 32 bytes long.
+	if len(idx.Entries) != 4 || idx.Entries[0].Offset != 4096 {
+		t.Fatal("Unexpected index", idx.Entries)
+	}
+	for k, e := range idx.Entries {
+		if k > 0 && !e.Time.After(idx.Entries[k-1].Time) {
+			t.Error("Index isn't sorted", idx.Entries)
+		}
+	}
+
+	if err := idx.save(dir); err != nil {
+		t.Fatal("Can't save index:", err)
+	}
+	loaded := loadIndex(dir, file, format)
+	if loaded == nil || len(loaded.Entries) != len(idx.Entries) {
+		t.Fatal("Can't load saved index")
+	}
+
+	i, err := findStartIndexed(file, loaded, options)
+	if err != nil {
+		t.Fatal("Can't start from index:", err)
+	}
+	i.Scan(options, format)
+	if i.Err != nil || i.Line != "2010-05-01T00:05:00Z line 300" {
+		t.Errorf("Started at %q, %v", i.Line, i.Err)
+	}
+
+	other, _ := retime.New("2006-01-02T15:04:05Z", time.UTC)
+	if loadIndex(dir, file, other) != nil {
+		t.Error("Loaded index of other format")
+	}
+
+	fmt.Fprintln(file, "2010-05-01T00:10:00Z line 600")
+	if loadIndex(dir, file, format) != nil {
+		t.Error("Loaded index of changed file")
+	}
+}
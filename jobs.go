@@ -0,0 +1,42 @@
+package main
+
+import (
+	"sync"
+)
+
+// parallel calls f for every k from 0 to n-1, in up to jobs goroutines
+// at once, and returns when all calls are done.
+func parallel(n, jobs int, f func(k int)) {
+	ks := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range ks {
+				f(k)
+			}
+		}()
+	}
+	for k := 0; k < n; k++ {
+		ks <- k
+	}
+	close(ks)
+	wg.Wait()
+}
+
+// startIterators runs starts in parallel and returns the iterators
+// they return in the order of starts, leaving out nil ones.
+func startIterators(starts []func() *Iterator, jobs int) Iterators {
+	started := make(Iterators, len(starts))
+	parallel(len(starts), jobs, func(k int) {
+		started[k] = starts[k]()
+	})
+	iterators := make(Iterators, 0, len(started))
+	for _, i := range started {
+		if i != nil {
+			iterators = append(iterators, i)
+		}
+	}
+	return iterators
+}
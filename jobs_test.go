@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStartIterators(t *testing.T) {
+	names := []string{"a", "", "c", "d", "", "f"}
+	var starts []func() *Iterator
+	for _, name := range names {
+		starts = append(starts, func() *Iterator {
+			if name == "" {
+				return nil
+			}
+			return newIterator(name, strings.NewReader(""), 0)
+		})
+	}
+
+	for _, jobs := range []int{1, 2, 10} {
+		var got []string
+		for _, i := range startIterators(starts, jobs) {
+			got = append(got, i.filename)
+		}
+		if strings.Join(got, " ") != "a c d f" {
+			t.Errorf("startIterators with %d jobs returned %v", jobs, got)
+		}
+	}
+}
@@ -4,21 +4,46 @@ import (
 	"bufio"
 	"compress/bzip2"
 	"compress/gzip"
+	"container/heap"
+	"encoding/csv"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/mdom/dtgrep/dateflag"
+	"github.com/mdom/dtgrep/dategrep"
 	"github.com/mdom/dtgrep/fixtime"
+	"github.com/mdom/dtgrep/ncompress"
 	"github.com/mdom/dtgrep/retime"
+	"github.com/ulikunitz/xz"
 	"io"
+	"io/fs"
+	"io/ioutil"
 	"log"
+	"math"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 var now = time.Now()
-var epoch time.Time
+
+// epoch is used as start of the date range if --from isn't given. It lies
+// before every date a format can return, even those with year zero.
+var epoch = dategrep.Epoch
+
+// future is used as end of the date range if --to isn't given and
+// --allow-future is set.
+var future = time.Unix(math.MaxInt64/2, 0)
 var loc = time.Local
 
 var Version = "unknown"
@@ -26,83 +51,475 @@ var CommitHash = "unknown"
 var BuildDate = "unknown"
 
 type Options struct {
-	from, to     time.Time
-	skipDateless bool
-	multiline    bool
+	from, to       time.Time
+	skipDateless   bool
+	skipMalformed  bool
+	warnDateless   bool
+	multiline      bool
+	window         dateflag.WindowFlag
+	mergeLimit     int
+	skipHeader     bool
+	byteOffset     bool
+	tsv            bool
+	warnRegression bool
+	strictSorted   bool
+	human          bool
+
+	endpoints, endpointsPerFile bool
+
+	// count only counts the lines that would be printed.
+	count bool
+
+	retryOpen  int
+	retryDelay time.Duration
+
+	normalizeWhitespace bool
+
+	recordLines, timestampLine int
+
+	csv      bool
+	csvField int
+
+	// onePer limits the output to the first line in each interval,
+	// buckets holds the intervals already printed.
+	onePer  time.Duration
+	buckets map[int64]bool
+
+	// lastPer limits the output to the last line in each interval.
+	lastPer time.Duration
+
+	// datelessPrefix and datelessInheritTime mark the lines without
+	// date printed with --multiline.
+	datelessPrefix      string
+	datelessInheritTime bool
+
+	// maxSkew is the largest difference between the dates of a line
+	// and its previous line, excludeSkewed drops lines beyond it.
+	maxSkew       time.Duration
+	excludeSkewed bool
+
+	// secondaryKey finds a number in lines to order lines with equal
+	// dates.
+	secondaryKey *regexp.Regexp
+
+	// multilineRegex restricts --multiline to the lines without date
+	// it matches.
+	multilineRegex *regexp.Regexp
+
+	// coalesceJSON prints each dated line with its continuation lines
+	// as one JSON object.
+	coalesceJSON bool
+
+	// fromPercent and toPercent select a slice of seekable files by
+	// position instead of date.
+	fromPercent, toPercent float64
+
+	// datelessFile receives the lines ignored by --skip-dateless.
+	datelessFile io.Writer
+
+	// warnFuture is set if --to defaults to now and lines after now
+	// should be reported.
+	warnFuture bool
+
+	// limitMemory is the size of the lines kept in memory by options
+	// that buffer them before they are spilled to disk.
+	limitMemory int
+
+	// outputPrefix is expanded and printed before each dated line and,
+	// with prefixContinuation, before lines without date.
+	outputPrefix       string
+	prefixContinuation bool
+
+	// withFilename prints the file name before each line.
+	withFilename bool
+
+	// lineNumber prints the line number before each line.
+	lineNumber bool
+
+	// invert hides the lines between excludeFrom and excludeTo, while
+	// from and to span all dates.
+	invert                 bool
+	excludeFrom, excludeTo time.Time
+
+	// color highlights the timestamps of printed lines.
+	color bool
+
+	// quiet stops successfully at the first line instead of printing it.
+	quiet bool
+
+	// year is the year of dates of formats without year. If it's zero,
+	// the year is guessed from now.
+	year int
+
+	// maxCount stops printing after that many lines, counted per file
+	// if maxCountPerFile is set and over all files otherwise.
+	maxCount        int
+	maxCountPerFile bool
+
+	// reverse prints the lines newest first after all were read.
+	reverse bool
+
+	// prefilter skips files without bisecting them if their first and
+	// last date are both outside the range.
+	prefilter bool
+
+	// contextBefore and contextAfter widen from and to, the range of
+	// hits between them is kept in hitsFrom and hitsTo.
+	contextBefore, contextAfter time.Duration
+	hitsFrom, hitsTo            time.Time
+
+	// outputLocation is the location timestamps are converted to before
+	// printing, if it's set.
+	outputLocation *time.Location
+}
+
+// continues reports whether line without date continues the line before
+// it with --multiline.
+func (o Options) continues(line string) bool {
+	return o.multiline && (o.multilineRegex == nil || o.multilineRegex.MatchString(line))
+}
+
+// skips reports whether a line with the date error err is ignored.
+// Lines with a malformed date are only ignored with --on-error.
+func (o Options) skips(err error) bool {
+	if err == retime.ErrNoMatch {
+		return o.skipDateless
+	}
+	return o.skipMalformed
+}
+
+// passes reports whether a line with the date error err is passed over
+// while bisecting or sorting. There, a line might also belong to a long
+// header.
+func (o Options) passes(line string, err error) bool {
+	return err == retime.ErrNoMatch && o.continues(line) || o.skips(err) || o.skipHeader
+}
+
+// hasContext reports whether context lines are printed around the
+// hits.
+func (o Options) hasContext() bool {
+	return o.contextBefore > 0 || o.contextAfter > 0
+}
+
+// needsLineNumbers reports whether files have to be read from their start
+// to count lines.
+func (o Options) needsLineNumbers() bool {
+	return o.lineNumber || strings.Contains(o.outputPrefix, "{line}")
 }
 
 type Iterator struct {
 	filename string
 	reader   io.Reader
+	out      io.Writer
 	*bufio.Scanner
 	Line string
 	Time time.Time
 	Err  error
+
+	// Offset is the position of Line in the (uncompressed) input, pos
+	// the position after it.
+	Offset, pos int64
+
+	// Lineno is the number of Line counted from where reading started,
+	// lines the number of lines read so far.
+	Lineno, lines int
+
+	// previous is the date of the previous dated line.
+	previous time.Time
+
+	// recordLines is the number of lines read at once, the date is
+	// searched in the line timestampLine of them.
+	recordLines, timestampLine int
+
+	// format is used to find the dates of this file.
+	format retime.Format
+
+	// reference is the last date accepted by --max-skew.
+	reference time.Time
+
+	// ahead holds the lines read by --max-skew to compare Line with the
+	// lines after it, read is the position of the scanner in the input.
+	ahead []aheadLine
+	read  int64
+
+	// key is the number found by --secondary-key in Line. It orders
+	// lines with equal dates before priority.
+	key float64
+
+	// priority decides which of two lines with equal dates is printed
+	// first, lower values win. It defaults to the position of the file
+	// in the arguments.
+	priority int
+
+	// endpoints are the first and last line of this file.
+	endpoints endpoints
+
+	// dated is set once a line with a date was read.
+	dated bool
+
+	// visible is false if the last dated line was outside of the daily
+	// window or excluded by --exclude-skewed, so its continuation lines
+	// are suppressed as well.
+	visible bool
+
+	// printed counts the lines printed from this file.
+	printed int
+
+	// start is the position reading started at.
+	start int64
+}
+
+func newIterator(filename string, r io.Reader, offset int64) *Iterator {
+	i := &Iterator{filename: filename, reader: r, pos: offset, read: offset, start: offset, out: os.Stdout}
+	i.Scanner = newScanner(r)
+	i.Scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		i.read += int64(advance)
+		return advance, token, err
+	})
+	return i
 }
 
 type Iterators []*Iterator
 
-func (it Iterators) Len() int           { return len(it) }
-func (it Iterators) Swap(i, j int)      { it[i], it[j] = it[j], it[i] }
-func (it Iterators) Less(i, j int) bool { return it[i].Time.Before(it[j].Time) }
+func (it Iterators) Len() int      { return len(it) }
+func (it Iterators) Swap(i, j int) { it[i], it[j] = it[j], it[i] }
+func (it Iterators) Less(i, j int) bool {
+	if it[i].Time.Equal(it[j].Time) {
+		if it[i].key != it[j].key {
+			return it[i].key < it[j].key
+		}
+		return it[i].priority < it[j].priority
+	}
+	return it[i].Time.Before(it[j].Time)
+}
+
+// Push and Pop implement heap.Interface, so the iterator with the
+// earliest line is found in logarithmic time.
+func (it *Iterators) Push(x interface{}) { *it = append(*it, x.(*Iterator)) }
+func (it *Iterators) Pop() interface{} {
+	old := *it
+	i := old[len(old)-1]
+	*it = old[:len(old)-1]
+	return i
+}
+
+// next returns the iterator following the first of the heap it, or nil
+// if there is none.
+func (it Iterators) next() *Iterator {
+	switch {
+	case len(it) < 2:
+		return nil
+	case len(it) > 2 && it.Less(2, 1):
+		return it[2]
+	}
+	return it[1]
+}
 
 func inTimeRange(s *Iterator, from, to time.Time) bool {
-	dt := s.Time
-	return (dt.Equal(from) || dt.After(from)) && dt.Before(to)
+	return dategrep.InRange(s.Time, from, to)
 }
 
-func filter(s Iterators, from, to time.Time) Iterators {
+var futureWarned bool
+
+func filter(s Iterators, options Options) Iterators {
 	var p Iterators
 	for _, v := range s {
-		if v.Err == nil && inTimeRange(v, from, to) {
+		if active(v, options) {
 			p = append(p, v)
 		}
 	}
 	return p
 }
 
+// active reports whether the current line of v is in the date range.
+func active(v *Iterator, options Options) bool {
+	if v.Err == nil && inTimeRange(v, options.from, options.to) {
+		return true
+	}
+	// Iterators stop at the first line after the range, so its time
+	// is still set, even if Scan marked the iterator as exhausted.
+	if options.warnFuture && !futureWarned && v.Time.After(now) {
+		futureWarned = true
+		log.Println("Warning: Skipped lines dated after now. Use --allow-future to print them.")
+	}
+	return false
+}
+
+var granularities = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+}
+
 var formats = map[string]string{
-	"rsyslog": "Jan _2 15:04:05",
-	"rfc3339": time.RFC3339,
-	"apache":  "02/Jan/2006:15:04:05 -0700",
+	"rsyslog":    "Jan _2 15:04:05",
+	"rfc3339":    time.RFC3339,
+	"apache":     "02/Jan/2006:15:04:05 -0700",
+	"rfc1123":    time.RFC1123,
+	"rfc822":     time.RFC822,
+	"ansic":      time.ANSIC,
+	"unixdate":   time.UnixDate,
+	"euro":       "02.01.2006 15:04:05",
+	"nginx":      "[02/Jan/2006:15:04:05 -0700]",
+	"syslog5424": "2006-01-02T15:04:05.999999Z07:00",
 }
 
-func dateRange(from, to time.Time, duration time.Duration) (time.Time, time.Time) {
+// addFormats adds named formats from a list like
+// "name=layout;name2=layout2". Malformed entries are skipped with a
+// warning.
+func addFormats(formats map[string]string, list string) {
+	for _, entry := range strings.Split(list, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || parts[1] == "" {
+			log.Println("Warning: Skipping malformed format", entry)
+			continue
+		}
+		formats[strings.TrimSpace(parts[0])] = parts[1]
+	}
+}
 
-	// --duration, --from and --to specified
-	if duration != 0 && !to.IsZero() && !from.IsZero() {
-		log.Fatalln("--duration can only be used with either --from or --to.")
+// numericFormats are the named formats of numbers instead of a layout.
+var numericFormats = map[string]struct {
+	description string
+	format      func() retime.Format
+}{
+	"mjd":      {"Modified Julian Date like 55317.5", retime.NewMJD},
+	"jd":       {"Julian Date like 2455318.0", retime.NewJD},
+	"epoch":    {"seconds since the Unix epoch", func() retime.Format { return retime.NewEpoch(time.Second) }},
+	"epoch-ms": {"milliseconds since the Unix epoch", func() retime.Format { return retime.NewEpoch(time.Millisecond) }},
+	"epoch-ns": {"nanoseconds since the Unix epoch", func() retime.Format { return retime.NewEpoch(time.Nanosecond) }},
+}
+
+// newFormat returns the named format or, if there's none called name,
+// the format with name as layout.
+func newFormat(name, pattern string, yearPivot int, strict bool) retime.Format {
+	var format retime.Format
+	var err error
+	if template, ok := formats[name]; ok {
+		format, err = retime.New(template, loc)
+	} else if numeric, ok := numericFormats[name]; ok {
+		format = numeric.format()
+	} else {
+		format, err = retime.New(name, loc)
+	}
+	if err != nil {
+		fatalln("Can't create format:", err)
 	}
 
-	// only --duration specified
-	if duration != 0 && to.IsZero() && from.IsZero() {
-		switch {
-		case duration.Hours() >= 1:
-			to = now.Truncate(time.Duration(1) * time.Hour)
-		case duration.Minutes() >= 1:
-			to = now.Truncate(time.Duration(1) * time.Minute)
-		default:
-			to = now.Truncate(time.Duration(1) * time.Second)
+	if pattern != "" {
+		format, err = format.WithPattern(pattern)
+		if err != nil {
+			fatalln("Can't compile pattern:", err)
+		}
+	}
+
+	format = format.WithYearPivot(yearPivot)
+
+	if strict {
+		format = format.Anchor()
+	}
+	return format
+}
+
+// defaultFormatRules are used by --format-by-name after the rules in
+// GO_DATEGREP_FORMAT_RULES.
+const defaultFormatRules = "*access.log*=apache;*access_log*=apache;syslog*=rsyslog;messages*=rsyslog"
+
+// formatRule selects the format of files whose base name matches the
+// glob pattern.
+type formatRule struct {
+	pattern, name string
+	format        retime.Format
+}
+
+type formatRules []formatRule
+
+// parseFormatRules parses a list like "pattern=format;pattern2=format2".
+// Malformed entries are skipped with a warning.
+func parseFormatRules(list string) formatRules {
+	var rules formatRules
+	for _, entry := range strings.Split(list, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		rule, err := parseFormatRule(entry)
+		if err != nil {
+			log.Println("Warning: Skipping malformed format rule", entry)
+			continue
 		}
-		from = to.Add(-duration)
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func parseFormatRule(entry string) (formatRule, error) {
+	parts := strings.SplitN(entry, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || parts[1] == "" {
+		return formatRule{}, errors.New("expected pattern=format")
 	}
+	if _, err := path.Match(parts[0], ""); err != nil {
+		return formatRule{}, err
+	}
+	return formatRule{pattern: strings.TrimSpace(parts[0]), name: parts[1]}, nil
+}
+
+func (rules *formatRules) String() string {
+	return ""
+}
 
-	if duration != 0 && !to.IsZero() && from.IsZero() {
-		from = to.Add(-duration)
+// Set adds a rule like "pattern=format" given with --format-for.
+func (rules *formatRules) Set(entry string) error {
+	rule, err := parseFormatRule(entry)
+	if err != nil {
+		return err
 	}
+	*rules = append(*rules, rule)
+	return nil
+}
 
-	if duration != 0 && to.IsZero() && !from.IsZero() {
-		to = from.Add(duration)
+// match returns the format of the first rule matching filename or
+// fallback.
+func (rules formatRules) match(filename string, fallback retime.Format) retime.Format {
+	for _, rule := range rules {
+		if ok, _ := path.Match(rule.pattern, path.Base(filename)); ok {
+			return rule.format
+		}
 	}
+	return fallback
+}
 
-	if to.IsZero() {
-		to = now
+// dayOfFilename returns the start and end of the day whose date in
+// format is part of the base name of filename. ok is false if the name
+// contains no date.
+func dayOfFilename(filename string, format retime.Format, options Options) (start, end time.Time, ok bool) {
+	dt, err := extract(path.Base(filename), Options{year: options.year}, format)
+	if err != nil {
+		return start, end, false
 	}
+	y, m, d := dt.Date()
+	start = time.Date(y, m, d, 0, 0, 0, 0, dt.Location())
+	return start, start.AddDate(0, 0, 1), true
+}
 
+func dateRange(from, to time.Time, duration time.Duration) (time.Time, time.Time) {
+	from, to, err := dategrep.DateRange(from, to, duration, now)
+	if err != nil {
+		fatalln("--duration can only be used with either --from or --to.")
+	}
 	return from, to
 }
 
 func main() {
+	exit(run())
+}
+
+// run searches the files and returns the exit status: 0 if lines were
+// printed and 1 if not. Errors exit with status 2 like grep.
+func run() (status int) {
 
 	log.SetFlags(0)
 	log.SetPrefix("")
@@ -112,7 +529,10 @@ func main() {
 	toFlag := dateflag.DateFlag{Now: now}
 	fromFlag := dateflag.DateFlag{Now: now}
 
-	var duration time.Duration
+	aroundFlag := dateflag.DateFlag{Now: now}
+	nowFlag := dateflag.DateFlag{Now: now}
+
+	var durationFlag dateflag.DurationFlag
 
 	var options Options
 
@@ -124,240 +544,2748 @@ func main() {
 	flag.Var(&fromFlag, "from", "Print all lines from `DATESPEC` inclusively.")
 	flag.Var(&toFlag, "to", "Print all lines until `DATESPEC` exclusively.")
 
+	var fromDate, fromTime, toDate, toTime string
+	flag.StringVar(&fromDate, "from-date", "", "Print all lines from `DATE` like 2006-01-02.")
+	flag.StringVar(&fromTime, "from-time", "", "Print all lines from `TIME` like 15:04.")
+	flag.StringVar(&toDate, "to-date", "", "Print all lines until `DATE` like 2006-01-02.")
+	flag.StringVar(&toTime, "to-time", "", "Print all lines until `TIME` like 15:04.")
+
 	flag.StringVar(&formatName, "format", defaultFormat, "Use `FORMAT` to parse file.")
 	flag.BoolVar(&options.skipDateless, "skip-dateless", false, "Ignore all lines without timestamp.")
+
+	var onError string
+	flag.StringVar(&onError, "on-error", "abort", "Either abort, warn about or skip lines without date: `ACTION`.")
 	flag.BoolVar(&options.multiline, "multiline", false, "Print all lines between the start and end line even if they are not timestamped.")
+	flag.StringVar(&options.datelessPrefix, "dateless-prefix", "", "Print `STRING` before lines without date.")
+	flag.BoolVar(&options.datelessInheritTime, "dateless-inherit-timestamp", false, "Print the date of the previous line before lines without date.")
+	flag.BoolVar(&options.coalesceJSON, "coalesce-multiline-into-json", false, "Print each line with the following lines without date as JSON object.")
 	flag.StringVar(&location, "location", time.Local.String(), "Use location in the absence of any timezone information.")
 
-	flag.DurationVar(&duration, "duration", 0, "Print all lines in `DURATION` from --from or --to.")
+	var outputTimezone string
+	flag.StringVar(&outputTimezone, "output-timezone", "", "Print timestamps converted to the location `TZ`.")
+
+	flag.Var(&aroundFlag, "around", "Print all lines within --duration before and after `DATESPEC`.")
+	flag.Var(&durationFlag, "duration", "Print all lines in `DURATION` from --from or --to, like 1h30m or PT1H30M.")
+	flag.DurationVar(&options.onePer, "one-per", 0, "Only print the first line in each `INTERVAL`, like 1m or 1h.")
+	flag.DurationVar(&options.lastPer, "last-per", 0, "Only print the last line in each `INTERVAL`, like 1m or 1h.")
+	flag.DurationVar(&options.contextBefore, "context-before", 0, "Also print the lines `DURATION` before the range.")
+	flag.DurationVar(&options.contextAfter, "context-after", 0, "Also print the lines `DURATION` after the range.")
+	flag.Var(&options.window, "daily-window", "Only print lines whose time of day is within `START-END`, e.g. 09:00-17:00.")
+
+	flag.BoolVar(&options.byteOffset, "byte-offset", false, "Print the byte offset of each line within its file.")
+	flag.StringVar(&options.outputPrefix, "output-prefix", "", "Print `TEMPLATE` with {file}, {line}, {ts} and {epoch} replaced before each line.")
+	flag.BoolVar(&options.prefixContinuation, "prefix-continuation", false, "Print --output-prefix also before lines without date.")
+	flag.BoolVar(&options.byteOffset, "b", false, "Shorthand for --byte-offset.")
+	flag.BoolVar(&options.invert, "invert-match", false, "Print the lines outside of the date range instead.")
+	flag.BoolVar(&options.invert, "v", false, "Shorthand for --invert-match.")
+	flag.BoolVar(&options.lineNumber, "line-number", false, "Print the line number of each line within its file.")
+	flag.BoolVar(&options.lineNumber, "n", false, "Shorthand for --line-number.")
+	var noGlob bool
+	flag.BoolVar(&noGlob, "no-glob", false, "Don't expand glob patterns in file arguments.")
+
+	var recursive bool
+	flag.BoolVar(&recursive, "recursive", false, "Read all files below directories given as input.")
+	flag.BoolVar(&recursive, "r", false, "Shorthand for --recursive.")
+
+	var filesFrom string
+	flag.StringVar(&filesFrom, "files-from", "", "Read the names of input files from `FILE`, one per line, or from stdin if it's -.")
+
+	var noMerge bool
+	flag.BoolVar(&noMerge, "no-merge", false, "Print the lines of each file in argument order instead of merging them by date.")
+
+	var sortFiles bool
+	flag.BoolVar(&sortFiles, "sort-files", false, "Print the files of --no-merge ordered by their first matching line.")
+
+	var follow bool
+	flag.BoolVar(&follow, "follow", false, "Wait for lines appended to the file like tail -f.")
+
+	var color string
+	flag.StringVar(&color, "color", "auto", "Highlight timestamps `WHEN`: auto, always or never.")
+
+	var withFilename, noFilename bool
+	flag.BoolVar(&withFilename, "with-filename", false, "Print the file name before each line, the default with multiple files.")
+	flag.BoolVar(&withFilename, "H", false, "Shorthand for --with-filename.")
+	flag.BoolVar(&noFilename, "no-filename", false, "Never print the file name before lines.")
+	flag.BoolVar(&noFilename, "h", false, "Shorthand for --no-filename.")
+	flag.Float64Var(&options.fromPercent, "from-percent", 0, "Print lines starting at `PERCENT` of the file size, ignoring dates.")
+	flag.Float64Var(&options.toPercent, "to-percent", 100, "Print lines starting before `PERCENT` of the file size, ignoring dates.")
+	flag.BoolVar(&options.tsv, "tsv", false, "Print epoch seconds, file name and line separated by tabs.")
+	flag.BoolVar(&options.normalizeWhitespace, "normalize-whitespace", false, "Collapse whitespace to single spaces before searching dates.")
+	flag.BoolVar(&options.skipHeader, "auto-skip-header", false, "Ignore lines without timestamp at the start of each file.")
+	flag.IntVar(&options.mergeLimit, "merge-limit", 0, "Stop after printing `N` lines over all files.")
+	flag.IntVar(&options.maxCount, "max-count", 0, "Stop after printing `N` lines, per file with --no-merge.")
+	flag.BoolVar(&options.reverse, "reverse", false, "Print the newest lines first.")
+
+	flag.IntVar(&options.recordLines, "record-lines", 1, "Treat every `N` lines as one record.")
+	flag.IntVar(&options.timestampLine, "timestamp-line", 1, "Search the timestamp in line `N` of each record.")
+	flag.IntVar(&options.limitMemory, "limit-memory", 0, "Spill buffered lines to disk beyond `BYTES`.")
+
+	var pattern string
+	flag.StringVar(&pattern, "pattern", "", "Search timestamps with the regular expression `REGEXP` and parse them with --format.")
+
+	flag.BoolVar(&options.human, "human", false, "Print durations in words, like 1 hour 23 minutes.")
+	flag.BoolVar(&options.quiet, "quiet", false, "Print nothing and exit successfully at the first matching line.")
+	flag.BoolVar(&options.quiet, "q", false, "Shorthand for --quiet.")
+	flag.BoolVar(&options.count, "count", false, "Only print the number of matching lines.")
+	flag.BoolVar(&options.count, "c", false, "Shorthand for --count.")
+	flag.BoolVar(&options.endpoints, "endpoints", false, "Only print the first and the last line.")
+	flag.BoolVar(&options.endpointsPerFile, "endpoints-per-file", false, "Only print the first and the last line of each file.")
+	flag.BoolVar(&options.strictSorted, "strict-sorted", false, "Abort if a line isn't dated after its previous line.")
+	flag.BoolVar(&options.warnRegression, "warn-on-regression", false, "Warn about lines dated before their previous line.")
+
+	flag.IntVar(&options.retryOpen, "retry-open", 0, "Retry opening files `N` times on transient errors.")
+	flag.DurationVar(&options.retryDelay, "retry-delay", time.Second, "Wait `DURATION` before the first retry, doubling it for each further one.")
+
+	var output, syslogAddress, syslogPriority string
+	flag.StringVar(&output, "output", "stdout", "Print lines to `OUTPUT`, either stdout or syslog.")
+	flag.StringVar(&syslogAddress, "syslog-address", "", "Send lines to the syslog daemon at `NETWORK://HOST:PORT` instead of the local one.")
+	flag.StringVar(&syslogPriority, "syslog-priority", "user.info", "Send lines with `FACILITY.SEVERITY` to syslog.")
+
+	var cacheIndex string
+	flag.StringVar(&cacheIndex, "cache-index", "", "Save an index of each file in `DIR` to skip the binary search next time.")
+
+	var checkpointPath string
+	flag.StringVar(&checkpointPath, "checkpoint", "", "Save the position after the last line to `FILE` and resume from it.")
+
+	flag.IntVar(&bufferSize, "buffer-size", bufferSize, "Read lines up to `BYTES` long.")
+
+	var jobs int
+	flag.IntVar(&jobs, "jobs", runtime.GOMAXPROCS(0), "Search up to `N` files at once.")
+
+	var pager string
+	flag.StringVar(&pager, "pager", "auto", "Print through $PAGER `WHEN`: auto if stdout is a terminal, always or never.")
+
+	var webhookURL string
+	flag.StringVar(&webhookURL, "webhook", "", "Post each printed line as JSON to `URL`.")
+
+	var listFiles bool
+	flag.BoolVar(&listFiles, "list-files", false, "Print the type and name of each input file and exit.")
+
+	var granularity string
+	flag.StringVar(&granularity, "granularity", "", "Compare dates only up to the `UNIT` second, minute or hour.")
+
+	flag.Var(&maxPer, "max-per", "Only print the first `INTERVAL:K` lines in each interval, like 1m:10.")
+	flag.BoolVar(&maxPer.marker, "max-per-marker", false, "Print the number of lines dropped by --max-per after each interval.")
+
+	flag.DurationVar(&options.maxSkew, "max-skew", 0, "Warn about lines dated more than `DURATION` away from the lines around them.")
+	flag.BoolVar(&options.excludeSkewed, "exclude-skewed", false, "Don't print lines reported by --max-skew.")
+
+	var multilinePattern string
+	flag.StringVar(&multilinePattern, "multiline-regex", "", "Only print lines without date matching `REGEXP` with the line before them.")
+
+	var secondaryKeyPattern string
+	flag.StringVar(&secondaryKeyPattern, "secondary-key", "", "Order lines with equal dates by the number captured by `REGEXP`.")
+
+	var stdinPriority string
+	flag.StringVar(&stdinPriority, "stdin-priority", "", "Print lines from stdin `first` or last if their date equals lines from files.")
+
+	var preSort bool
+	flag.BoolVar(&preSort, "pre-sort", false, "Sort the lines of a single unsorted file by date before searching.")
+
+	var noPrefilter bool
+	flag.BoolVar(&noPrefilter, "no-prefilter", false, "Search files even if their first and last date are outside the range.")
+
+	var splitDir string
+	flag.StringVar(&splitDir, "split-output-dir", "", "Write the lines of each file to its own file in `DIR`.")
+
+	flag.BoolVar(&options.csv, "csv", false, "Parse lines as CSV and search the timestamp in the field given by --csv-field.")
+	flag.IntVar(&options.csvField, "csv-field", 1, "Search the timestamp in CSV field `N`.")
+
+	flag.IntVar(&options.year, "year", 0, "Use `YYYY` as year of formats without year instead of the current one.")
+	var yearPivot int
+	flag.IntVar(&yearPivot, "year-pivot", 69, "Put two-digit years below `N` in the 21st century, the others in the 20th.")
+
+	var formatFor formatRules
+	flag.Var(&formatFor, "format-for", "Use the format for files matching `PATTERN=FORMAT`, like 'access.*=apache'.")
+
+	var formatByName bool
+	flag.BoolVar(&formatByName, "format-by-name", false, "Choose the format of each file by its name.")
+
+	var filenameFormat string
+	flag.StringVar(&filenameFormat, "filename-format", "", "Skip files whose name contains a date in `FORMAT` of a day outside the range.")
+
+	var strictFormat bool
+	flag.BoolVar(&strictFormat, "strict-format", false, "Only accept timestamps at the start of a line.")
+
+	var datelessFile string
+	flag.StringVar(&datelessFile, "dateless-file", "", "Write lines ignored by --skip-dateless to `FILE`.")
+
+	flag.Var(&nowFlag, "now", "Use `DATESPEC` as current time instead of the clock.")
+
+	var relativeTo string
+	flag.StringVar(&relativeTo, "relative-to", "now", "Resolve datespecs relative to `WHEN`, either now or the last date in the files.")
+
+	var allowFuture bool
+	flag.BoolVar(&allowFuture, "allow-future", false, "Print lines dated after now if --to isn't given.")
+
+	flag.StringVar(&errorFormat, "error-format", "text", "Print errors and warnings as `FORMAT`, either text or json.")
+	var collect bool
+	flag.BoolVar(&collect, "collect-errors", false, "Print the JSON error records as a single array at the end.")
+
+	var showStats bool
+	flag.BoolVar(&showStats, "stats", false, "Print the matched range and the number of lines to stderr at the end.")
+
+	var listFormats bool
+	flag.BoolVar(&listFormats, "list-formats", false, "Print the named formats and their layouts and exit.")
 
 	var displayVersion bool
 	flag.BoolVar(&displayVersion, "version", false, "Display version")
 
+	flag.String("config", "", "Read default options from `FILE` instead of ~/.config/dtgrep/config.")
+
 	flag.Lookup("to").DefValue = "now"
 	flag.Lookup("from").DefValue = "epoch"
 
+	// The configuration file sets defaults before the arguments are
+	// parsed, so they can override it.
+	if config := findConfig(flag.CommandLine, os.Args[1:]); config != "" {
+		if err := loadConfig(flag.CommandLine, config); err != nil {
+			fatalln("Cannot read configuration:", err)
+		}
+	} else if config := defaultConfig(); config != "" {
+		err := loadConfig(flag.CommandLine, config)
+		if err != nil && !os.IsNotExist(err) {
+			fatalln("Cannot read configuration:", err)
+		}
+	}
+
 	flag.Parse()
 
-	if displayVersion {
-		log.Printf("version: %s\ncommit: %s\nbuild date: %s\n",
-			Version, CommitHash, BuildDate)
-		return
+	if nowFlag.Get().IsZero() && os.Getenv("GO_DATEGREP_NOW") != "" {
+		if err := nowFlag.Set(os.Getenv("GO_DATEGREP_NOW")); err != nil {
+			fatalln("Can't parse GO_DATEGREP_NOW:", err)
+		}
+	}
+	// The datespecs were parsed while the flags were read, so they have
+	// to be evaluated again relative to the new time.
+	if !nowFlag.Get().IsZero() {
+		now = nowFlag.Get()
+		if err := fromFlag.SetNow(now); err != nil {
+			fatalln("Can't parse --from:", err)
+		}
+		if err := toFlag.SetNow(now); err != nil {
+			fatalln("Can't parse --to:", err)
+		}
+		if err := aroundFlag.SetNow(now); err != nil {
+			fatalln("Can't parse --around:", err)
+		}
 	}
 
-	var err error
+	if bufferSize < 1 {
+		fatalln("--buffer-size must be positive.")
+	}
 
-	loc, err = time.LoadLocation(location)
-	if err != nil {
-		log.Fatalln("Can't load location:", err)
+	if jobs < 1 {
+		fatalln("--jobs must be positive.")
 	}
 
-	options.from, options.to = dateRange(fromFlag.Get(), toFlag.Get(), duration)
+	if options.maxCount < 0 {
+		fatalln("--max-count must not be negative.")
+	}
+	options.maxCountPerFile = noMerge
+	options.prefilter = !noPrefilter
 
-	if options.from.After(options.to) || options.from.Equal(options.to) {
-		log.Fatalln("Start date must be before end date.")
+	if sortFiles && !noMerge {
+		fatalln("--sort-files can only be used with --no-merge.")
 	}
 
-	var format retime.Format
-	for name, template := range formats {
-		if name == formatName {
-			format, err = retime.New(template, loc)
-			if err != nil {
-				log.Fatalln("Can't create format:", err)
-			}
-			break
+	if options.reverse {
+		if follow {
+			fatalln("--reverse can't be used with --follow.")
 		}
+		if noMerge {
+			fatalln("--reverse can't be used with --no-merge.")
+		}
+		reversed.max, reversed.limit = options.maxCount, options.limitMemory
 	}
 
-	if (format == retime.Format{}) {
-		format, err = retime.New(formatName, loc)
-		if err != nil {
-			log.Fatalln("Can't create format:", err)
+	duration := durationFlag.Get()
+
+	if fromDate != "" || fromTime != "" {
+		if !fromFlag.Get().IsZero() {
+			fatalln("--from can't be used with --from-date or --from-time.")
+		}
+		if err := fromFlag.SetParts(fromDate, fromTime); err != nil {
+			fatalln("Can't parse --from-date or --from-time:", err)
+		}
+	}
+	if toDate != "" || toTime != "" {
+		if !toFlag.Get().IsZero() {
+			fatalln("--to can't be used with --to-date or --to-time.")
+		}
+		if err := toFlag.SetParts(toDate, toTime); err != nil {
+			fatalln("Can't parse --to-date or --to-time:", err)
 		}
 	}
 
-	var iterators = make(Iterators, 0)
+	if !aroundFlag.Get().IsZero() {
+		if !fromFlag.Get().IsZero() || !toFlag.Get().IsZero() {
+			fatalln("--around can't be used with --from or --to.")
+		}
+		if duration <= 0 {
+			fatalln("--around needs a positive --duration.")
+		}
+	}
 
-	if len(flag.Args()) > 0 {
-		for _, filename := range flag.Args() {
+	if displayVersion {
+		log.Printf("version: %s\ncommit: %s\nbuild date: %s\n",
+			Version, CommitHash, BuildDate)
+		return
+	}
 
-			if filename == "-" {
-				i := &Iterator{filename: filename, reader: os.Stdin, Scanner: bufio.NewScanner(os.Stdin)}
-				iterators = append(iterators, i)
-				continue
-			}
+	if collect && flagSet("error-format") && errorFormat != "json" {
+		fatalln("--collect-errors can only be used with --error-format json.")
+	}
+	if collect {
+		collectErrors, errorFormat = true, "json"
+	}
 
-			file, err := os.Open(filename)
-			if err != nil {
-				log.Fatalln("Cannot open", filename, ":", err)
-			}
-			defer file.Close()
+	switch errorFormat {
+	case "json":
+		log.SetOutput(jsonLog{})
+	case "text":
+	default:
+		fatalln("Unknown error format", errorFormat)
+	}
 
-			// mimeType support?
-			ext := path.Ext(filename)
-			if ext == ".gz" || ext == ".z" {
-				r, err := gzip.NewReader(file)
-				defer r.Close()
-				if err != nil {
-					log.Fatalln("Cannot open", filename, ":", err)
-				}
-				i := &Iterator{filename: filename, reader: r, Scanner: bufio.NewScanner(r)}
-				iterators = append(iterators, i)
-			} else if ext == ".bz2" || ext == ".bz" {
-				r := bzip2.NewReader(file)
-				i := &Iterator{filename: filename, reader: r, Scanner: bufio.NewScanner(r)}
-				iterators = append(iterators, i)
+	addFormats(formats, os.Getenv("GO_DATEGREP_FORMATS"))
+
+	if listFormats {
+		names := make([]string, 0, len(formats)+len(numericFormats))
+		for name := range formats {
+			names = append(names, name)
+		}
+		for name := range numericFormats {
+			if _, ok := formats[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if layout, ok := formats[name]; ok {
+				writeLine(os.Stdout, name+"\t"+layout)
 			} else {
-				scanner, err := findStartSeekable(file, options, format)
-				switch {
-				case err == io.EOF:
-					// daterange not in file, skip
-					continue
-				case err != nil:
-					log.Fatalln("Error finding dates in ", filename, ":", err)
-				}
-				i := &Iterator{filename: filename, reader: file, Scanner: scanner}
-				iterators = append(iterators, i)
+				writeLine(os.Stdout, name+"\t"+numericFormats[name].description)
 			}
 		}
-	} else {
-		i := &Iterator{filename: "-", reader: os.Stdin, Scanner: bufio.NewScanner(os.Stdin)}
-		iterators = append(iterators, i)
+		return
 	}
 
-	var ignoreError = options.skipDateless || options.multiline
-	for _, i := range iterators {
-		i.Scan(options.from, options.to, ignoreError, format)
+	args := flag.Args()
+	if !noGlob {
+		var err error
+		args, err = expandGlobs(args)
+		if err != nil {
+			fatalln("Cannot expand arguments:", err)
+		}
 	}
 
-	for {
-
-		iterators = filter(iterators, options.from, options.to)
-		sort.Sort(iterators)
-
-		if len(iterators) > 0 {
-			var until time.Time
-			if len(iterators) > 1 {
-				until = iterators[1].Time
-			} else {
-				until = options.to
+	if filesFrom != "" {
+		names, err := readFileList(filesFrom)
+		if err != nil {
+			fatalln("Cannot read", filesFrom, ":", err)
+		}
+		args = append(args, names...)
+		if filesFrom == "-" {
+			for _, arg := range args {
+				if arg == "-" {
+					fatalln("Can't read lines from stdin with --files-from -.")
+				}
 			}
-			i := iterators[0]
-			fmt.Println(i.Line)
-			i.Print(until, options, format)
-		} else {
-			break
+		}
+		// An empty list doesn't fall back to stdin.
+		if len(args) == 0 {
+			return 1
 		}
 	}
-}
 
-func (i *Iterator) Print(to time.Time, options Options, format retime.Format) {
-	for {
-		i.Line, i.Err = readline(i.Scanner)
-		if i.Err == io.EOF {
-			return
+	if recursive {
+		var err error
+		args, err = expandDirs(args)
+		if err != nil {
+			fatalln("Cannot read directory:", err)
 		}
-		if i.Err != nil {
-			// what file?
-			log.Fatalln("Error reading file:", i.Err)
+	}
+
+	// Files written separately by --split-output-dir aren't mixed.
+	options.withFilename = (withFilename || len(args) > 1 && splitDir == "") && !noFilename
+
+	if listFiles {
+		if len(args) == 0 {
+			args = []string{"-"}
+		}
+		for _, filename := range args {
+			writeLine(os.Stdout, fileType(filename)+"\t"+filename)
 		}
-		i.Time, i.Err = format.Extract(i.Line)
-		i.Time = fixtime.AddYear(i.Time, now)
+		return
+	}
 
-		switch {
-		case i.Err != nil && options.multiline:
-			fmt.Println(i.Line)
-		case i.Err != nil && options.skipDateless:
-			continue
+	var err error
+
+	loc, err = loadLocation(location)
+	if err != nil {
+		fatalln("Can't load location:", err)
+	}
+
+	if outputTimezone != "" {
+		options.outputLocation, err = loadLocation(outputTimezone)
+		if err != nil {
+			fatalln("Can't load --output-timezone:", err)
+		}
+		// The timestamp isn't necessarily the first one of the line.
+		if options.recordLines > 1 || options.csv {
+			fatalln("--output-timezone can't be used with --record-lines or --csv.")
+		}
+	}
+
+	if options.recordLines < 1 || options.timestampLine < 1 || options.timestampLine > options.recordLines {
+		fatalln("--timestamp-line must be between 1 and --record-lines.")
+	}
+
+	if options.limitMemory < 0 {
+		fatalln("--limit-memory must not be negative.")
+	}
+
+	if options.endpointsPerFile {
+		options.endpoints = true
+	}
+
+	if options.csvField < 1 {
+		fatalln("--csv-field must be at least 1.")
+	}
+
+	if options.onePer < 0 {
+		fatalln("--one-per must be positive.")
+	}
+	options.buckets = make(map[int64]bool)
+
+	if options.coalesceJSON {
+		options.multiline = true
+	}
+
+	if options.maxSkew < 0 {
+		fatalln("--max-skew must be positive.")
+	}
+
+	if secondaryKeyPattern != "" {
+		options.secondaryKey, err = regexp.Compile(secondaryKeyPattern)
+		if err != nil {
+			fatalln("Can't compile --secondary-key:", err)
+		}
+	}
+
+	if multilinePattern != "" {
+		options.multilineRegex, err = regexp.Compile(multilinePattern)
+		if err != nil {
+			fatalln("Can't compile --multiline-regex:", err)
+		}
+		options.multiline = true
+	}
+
+	if options.lastPer < 0 {
+		fatalln("--last-per must be positive.")
+	}
+	if options.lastPer > 0 && options.onePer > 0 {
+		fatalln("--one-per and --last-per can't be combined.")
+	}
+
+	// Warned lines are skipped as well, only bisection skips them
+	// silently as it might read lines several times.
+	switch onError {
+	case "abort":
+	case "warn":
+		options.skipDateless, options.skipMalformed, options.warnDateless = true, true, true
+	case "skip":
+		options.skipDateless, options.skipMalformed = true, true
+	default:
+		fatalln("--on-error must be abort, warn or skip.")
+	}
+
+	if datelessFile != "" {
+		if !options.skipDateless {
+			fatalln("--dateless-file can only be used with --skip-dateless.")
+		}
+		file, err := os.Create(datelessFile)
+		if err != nil {
+			fatalln("Cannot create", datelessFile, ":", err)
+		}
+		defer file.Close()
+		options.datelessFile = file
+	}
+
+	if flagSet("year") && (options.year < 1 || options.year > 9999) {
+		fatalln("--year must be between 1 and 9999.")
+	}
+
+	if stdinPriority != "" && stdinPriority != "first" && stdinPriority != "last" {
+		fatalln("--stdin-priority must be either first or last.")
+	}
+
+	if yearPivot < 0 || yearPivot > 100 {
+		fatalln("--year-pivot must be between 0 and 100.")
+	}
+
+	format := newFormat(formatName, pattern, yearPivot, strictFormat)
+
+	rules := formatFor
+	if formatByName {
+		rules = append(rules, parseFormatRules(os.Getenv("GO_DATEGREP_FORMAT_RULES")+";"+defaultFormatRules)...)
+	}
+	for i := range rules {
+		rules[i].format = newFormat(rules[i].name, pattern, yearPivot, strictFormat)
+	}
+
+	var nameFormat retime.Format
+	if filenameFormat != "" {
+		nameFormat = newFormat(filenameFormat, "", yearPivot, false)
+	}
+
+	to := toFlag.Get()
+	if relativeTo == "last" {
+		if len(args) == 0 {
+			fatalln("--relative-to last can't be used with stdin.")
+		}
+		var last time.Time
+		for _, filename := range args {
+			dt, err := lastDate(filename, options, rules.match(filename, format))
+			if err != nil {
+				fatalln("Error finding dates in", filename, ":", err)
+			}
+			if dt.After(last) {
+				last = dt
+			}
+		}
+		if last.IsZero() {
+			fatalln("No dates found in input files.")
+		}
+		// now is exclusive like --to, so it has to be after the last line.
+		now = last.Add(time.Nanosecond)
+		if err := fromFlag.SetNow(now); err != nil {
+			fatalln("Can't parse --from:", err)
+		}
+		if err := toFlag.SetNow(now); err != nil {
+			fatalln("Can't parse --to:", err)
+		}
+		if err := aroundFlag.SetNow(now); err != nil {
+			fatalln("Can't parse --around:", err)
+		}
+		to = toFlag.Get()
+		// Unlike with the wall clock, --duration ends at the last line.
+		if to.IsZero() && fromFlag.Get().IsZero() {
+			to = now
+		}
+	} else if relativeTo != "now" {
+		fatalln("--relative-to must be either now or last.")
+	}
+
+	if around := aroundFlag.Get(); !around.IsZero() {
+		options.from, options.to = around.Add(-duration), around.Add(duration)
+	} else {
+		options.from, options.to = dateRange(fromFlag.Get(), to, duration)
+	}
+
+	// Truncating the bounds is the same as truncating the dates of all
+	// lines, but keeps the binary search working.
+	if granularity != "" {
+		d, ok := granularities[granularity]
+		if !ok {
+			fatalln("--granularity must be second, minute or hour.")
+		}
+		if !options.from.Equal(epoch) {
+			options.from = truncateWall(options.from, d)
+		}
+		options.to = truncateWall(options.to, d).Add(d)
+	}
+
+	if toFlag.Get().IsZero() && duration == 0 {
+		if allowFuture || follow {
+			options.to = future
+		} else {
+			options.warnFuture = true
+		}
+	}
+
+	if options.from.After(options.to) || options.from.Equal(options.to) {
+		fatalln("Start date must be before end date.")
+	}
+
+	if options.invert {
+		options.excludeFrom, options.excludeTo = options.from, options.to
+		options.from, options.to = epoch, future
+		options.warnFuture = false
+	}
+
+	if options.contextBefore != 0 || options.contextAfter != 0 {
+		switch {
+		case options.contextBefore < 0 || options.contextAfter < 0:
+			fatalln("--context-before and --context-after must not be negative.")
+		case options.invert:
+			fatalln("--context-before and --context-after can't be used with --invert-match.")
+		case options.count || options.endpoints || options.endpointsPerFile:
+			fatalln("--context-before and --context-after can't be used with --count or --endpoints.")
+		case options.lastPer > 0 || options.coalesceJSON:
+			fatalln("--context-before and --context-after can't be used with --last-per or --coalesce-multiline-into-json.")
+		}
+		options.hitsFrom, options.hitsTo = options.from, options.to
+		if !options.from.Equal(epoch) {
+			options.from = options.from.Add(-options.contextBefore)
+		}
+		if !options.to.Equal(future) {
+			options.to = options.to.Add(options.contextAfter)
+		}
+	}
+
+	// Colors are decided before the pager replaces stdout.
+	switch color {
+	case "auto":
+		options.color = isTerminal(os.Stdout)
+	case "always":
+		options.color = true
+	case "never":
+	default:
+		fatalln("--color must be auto, always or never.")
+	}
+	if os.Getenv("NO_COLOR") != "" || splitDir != "" || output != "stdout" || options.coalesceJSON {
+		options.color = false
+	}
+
+	if options.quiet {
+		pager = "never"
+	}
+	switch pager {
+	case "auto", "always":
+		if pager == "auto" && !isTerminal(os.Stdout) {
+			break
+		}
+		stop, err := startPager()
+		if err != nil {
+			log.Println("Warning: Can't start pager:", err)
+			break
+		}
+		defer stop()
+	case "never":
+	default:
+		fatalln("--pager must be auto, always or never.")
+	}
+
+	// This runs after the deferred functions below printed pending
+	// lines.
+	defer func() {
+		if emitted == 0 && !stopped {
+			status = 1
+		}
+	}()
+
+	if (len(args) < 2 || noFilename) && plainSearch(args, options) {
+		grepPlain(args, options, format)
+		return
+	}
+
+	if showStats {
+		names := args
+		if len(names) == 0 {
+			names = []string{"-"}
+		}
+		defer stats.print(os.Stderr, names, options)
+	}
+
+	if options.fromPercent != 0 || options.toPercent != 100 {
+		if follow {
+			fatalln("--from-percent and --to-percent can't be used with --follow.")
+		}
+		if options.fromPercent < 0 || options.toPercent > 100 || options.fromPercent >= options.toPercent {
+			fatalln("Percentages must be between 0 and 100 and --from-percent must be less than --to-percent.")
+		}
+		if len(args) == 0 {
+			fatalln("--from-percent and --to-percent can't be used with stdin.")
+		}
+		if options.lineNumber {
+			fatalln("--from-percent and --to-percent can't be used with --line-number.")
+		}
+		if options.recordLines > 1 {
+			fatalln("--from-percent and --to-percent can't be used with --record-lines.")
+		}
+		for _, filename := range args {
+			if isURL(filename) {
+				fatalln("--from-percent and --to-percent can't be used with URLs.")
+			}
+			printSlice(filename, options, rules.match(filename, format))
+		}
+		return
+	}
+
+	if checkpointPath != "" {
+		if len(args) != 1 || fileType(args[0]) != "plain" {
+			fatalln("--checkpoint needs a single uncompressed file.")
+		}
+		if preSort {
+			fatalln("--checkpoint can't be used with --pre-sort.")
+		}
+		if options.lineNumber {
+			fatalln("--checkpoint can't be used with --line-number.")
+		}
+		progress.path = checkpointPath
+	}
+
+	if follow {
+		if len(args) != 1 || args[0] == "-" || fileType(args[0]) != "plain" {
+			fatalln("--follow needs a single uncompressed file.")
+		}
+		if preSort {
+			fatalln("--follow can't be used with --pre-sort.")
+		}
+	}
+
+	if preSort {
+		if len(args) != 1 || args[0] == "-" {
+			fatalln("--pre-sort needs a single file.")
+		}
+		if fileType(args[0]) != "plain" {
+			fatalln("--pre-sort can't be used with compressed files.")
+		}
+		if options.recordLines > 1 {
+			fatalln("--pre-sort can't be used with --record-lines.")
+		}
+	}
+
+	// Finding the start of a file is left to starts, which run in
+	// parallel. They return nil if the file has no lines in the range.
+	var starts []func() *Iterator
+	ready := func(i *Iterator) func() *Iterator {
+		return func() *Iterator { return i }
+	}
+
+	if len(args) > 0 {
+		for _, filename := range args {
+
+			if filename == "-" {
+				starts = append(starts, ready(newIterator(filename, os.Stdin, 0)))
+				continue
+			}
+
+			if filenameFormat != "" {
+				start, end, ok := dayOfFilename(filename, nameFormat, options)
+				if ok && (!end.After(options.from) || !start.Before(options.to)) {
+					continue
+				}
+			}
+
+			if isURL(filename) {
+				r, err := openURL(filename)
+				if err != nil {
+					fatalln("Cannot open", filename, ":", err)
+				}
+				defer r.Close()
+				starts = append(starts, ready(newIterator(filename, r, 0)))
+				continue
+			}
+
+			file, err := openFile(filename, options)
+			if err != nil {
+				fatalln("Cannot open", filename, ":", err)
+			}
+			defer file.Close()
+
+			if offset, ok := loadCheckpoint(checkpointPath, filename); ok {
+				if _, err := file.Seek(offset, os.SEEK_SET); err != nil {
+					fatalln("Cannot seek", filename, ":", err)
+				}
+				var r io.Reader = file
+				if follow {
+					r = followReader{file}
+				}
+				starts = append(starts, ready(newIterator(filename, r, offset)))
+				continue
+			}
+
+			if preSort {
+				sorted, err := sortLines(file, options, rules.match(filename, format))
+				if err != nil {
+					fatalln("Cannot sort", filename, ":", err)
+				}
+				tempFiles = append(tempFiles, sorted.Name())
+				defer os.Remove(sorted.Name())
+				defer sorted.Close()
+				file = sorted
+			}
+
+			if follow {
+				// Appended lines are never found by bisection, so the
+				// file is read from its start.
+				starts = append(starts, ready(newIterator(filename, followReader{file}, 0)))
+				continue
+			}
+
+			// records can't be found after seeking, line numbers are
+			// only known when reading from start and inverted ranges
+			// start there
+			streaming := options.recordLines > 1 || options.warnRegression || options.strictSorted || options.maxSkew > 0 ||
+				options.needsLineNumbers() || options.invert
+
+			fileFormat := rules.match(filename, format)
+
+			// mimeType support?
+			if c, ok := compressions[path.Ext(filename)]; ok {
+				if c.name == "gzip" && !streaming && !preSort {
+					blocks, err := readGzi(filename + ".gzi")
+					if err == nil {
+						starts = append(starts, func() *Iterator {
+							i, err := findStartBgzip(file, blocks, options, fileFormat)
+							switch {
+							case err == io.EOF:
+								return nil
+							case err != nil:
+								fatalln("Error finding dates in", filename, ":", err)
+							}
+							i.filename = filename
+							return i
+						})
+						continue
+					}
+					if !os.IsNotExist(err) {
+						log.Println("Warning: Reading", filename, "without index:", err)
+					}
+				}
+				r, err := c.newReader(file)
+				if err != nil {
+					fatalln("Cannot open", filename, ":", err)
+				}
+				starts = append(starts, ready(newIterator(filename, r, 0)))
+			} else if streaming || !isRegular(file) {
+				// pipes can't seek either
+				starts = append(starts, ready(newIterator(filename, file, 0)))
+			} else if cacheIndex != "" && !preSort {
+				starts = append(starts, func() *Iterator {
+					i, err := findStartCached(cacheIndex, file, options, fileFormat)
+					if err != nil && err != io.EOF {
+						fatalln("Error finding dates in", filename, ":", err)
+					}
+					if err == io.EOF {
+						return nil
+					}
+					i.filename = filename
+					return i
+				})
+			} else {
+				starts = append(starts, func() *Iterator {
+					i, err := findStartSeekable(file, options, fileFormat)
+					switch {
+					case err == io.EOF:
+						// daterange not in file, skip
+						return nil
+					case err != nil:
+						fatalln("Error finding dates in ", filename, ":", err)
+					}
+					i.filename = filename
+					return i
+				})
+			}
+		}
+	} else {
+		starts = append(starts, ready(newIterator("-", os.Stdin, 0)))
+	}
+
+	iterators := startIterators(starts, jobs)
+
+	if splitDir != "" {
+		names := args
+		if len(names) == 0 {
+			names = []string{"-"}
+		}
+		if err := os.MkdirAll(splitDir, 0755); err != nil {
+			fatalln("Cannot create", splitDir, ":", err)
+		}
+		outputs := make(map[string]io.Writer)
+		for k, output := range splitOutputNames(splitDir, names) {
+			file := &lazyFile{name: output}
+			defer file.Close()
+			outputs[names[k]] = file
+		}
+		for _, i := range iterators {
+			i.out = outputs[i.filename]
+		}
+	}
+
+	switch output {
+	case "syslog":
+		if splitDir != "" {
+			fatalln("--output syslog can't be used with --split-output-dir.")
+		}
+		w, err := newSyslogWriter(syslogAddress, syslogPriority)
+		if err != nil {
+			fatalln("Cannot connect to syslog:", err)
+		}
+		for _, i := range iterators {
+			i.out = w
+		}
+	case "stdout":
+	default:
+		fatalln("Unknown output", output)
+	}
+
+	for n, i := range iterators {
+		i.priority = n
+		if i.filename == "-" {
+			switch stdinPriority {
+			case "first":
+				i.priority = -1
+			case "last":
+				i.priority = len(iterators)
+			}
+		}
+		i.recordLines, i.timestampLine = options.recordLines, options.timestampLine
+		i.format = rules.match(i.filename, format)
+	}
+	parallel(len(iterators), jobs, func(k int) {
+		iterators[k].Scan(options, iterators[k].format)
+	})
+
+	files := iterators
+	if options.endpointsPerFile && !options.quiet {
+		defer func() {
+			for _, i := range files {
+				i.endpoints.print(i.out)
+			}
+		}()
+	} else if options.endpoints && !options.quiet {
+		defer allEndpoints.print(os.Stdout)
+	}
+	if options.count && !options.quiet {
+		names := args
+		if len(names) == 0 {
+			names = []string{"-"}
+		}
+		defer printCounts(names)
+	}
+	if webhookURL != "" {
+		hook = newWebhook(webhookURL)
+		defer hook.close()
+	}
+	defer reversed.flush()
+	defer progress.save()
+	defer maxPer.flush()
+	defer pending.flush()
+
+	// Exhausted iterators are dropped, so seekable and compressed files
+	// compete only with their current lines. The iterators are kept as
+	// heap with the earliest line first, equal dates are ordered by the
+	// priority of their iterators. Without merging, each file is printed
+	// completely in argument order.
+	iterators = filter(iterators, options)
+	if !noMerge {
+		heap.Init(&iterators)
+	} else if sortFiles {
+		// Scan stopped every iterator at its first line in the range.
+		sort.Sort(iterators)
+	}
+
+	for len(iterators) > 0 && !options.limitReached() {
+		until := options.to
+		if next := iterators.next(); next != nil && !noMerge {
+			until = next.Time
+		}
+		i := iterators[0]
+		i.visible = options.visible(i.Time)
+		i.emit(options)
+		i.Print(until, options, i.format)
+
+		switch {
+		case noMerge && (!active(i, options) || i.limitReached(options)):
+			iterators = iterators[1:]
+		case noMerge:
+		case active(i, options):
+			heap.Fix(&iterators, 0)
+		default:
+			heap.Pop(&iterators)
+		}
+	}
+	return
+}
+
+// plainFlags are the flags of a search package dategrep can do on its
+// own.
+var plainFlags = map[string]bool{
+	"from": true, "to": true, "duration": true, "now": true, "allow-future": true,
+	"format": true, "location": true, "pattern": true, "year-pivot": true, "strict-format": true,
+	"skip-dateless": true, "multiline": true, "buffer-size": true, "no-filename": true, "h": true,
+}
+
+// flagSet reports whether the flag name was given on the command line.
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// plainSearch reports whether args are searched without any options
+// beyond package dategrep, which takes regular files and stdin only.
+// It can't warn about lines in the future either.
+func plainSearch(args []string, options Options) bool {
+	plain := true
+	flag.Visit(func(f *flag.Flag) {
+		plain = plain && plainFlags[f.Name]
+	})
+	if !plain || options.warnFuture || options.color {
+		return false
+	}
+	for _, filename := range args {
+		if filename != "-" && (isURL(filename) || fileType(filename) != "plain") {
+			return false
+		}
+	}
+	return true
+}
+
+// grepPlain searches args with package dategrep. Regular files are
+// bisected before, so it starts reading them close to the range.
+func grepPlain(args []string, options Options, format retime.Format) {
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+	var names []string
+	var readers []io.Reader
+	for _, filename := range args {
+		if filename == "-" {
+			names, readers = append(names, filename), append(readers, os.Stdin)
+			continue
+		}
+		file, err := openFile(filename, options)
+		if err != nil {
+			fatalln("Cannot open", filename, ":", err)
+		}
+		defer file.Close()
+		if isRegular(file) {
+			i, err := findStartSeekable(file, options, format)
+			switch {
+			case err == io.EOF:
+				continue
+			case err != nil:
+				fatalln("Error finding dates in ", filename, ":", err)
+			}
+			if _, err := file.Seek(i.pos, os.SEEK_SET); err != nil {
+				fatalln("Cannot seek", filename, ":", err)
+			}
+		}
+		names, readers = append(names, filename), append(readers, file)
+	}
+
+	search := dategrep.Search{
+		SkipDateless: options.skipDateless,
+		Multiline:    options.multiline,
+		BufferSize:   bufferSize,
+		Now:          now,
+	}
+	err := search.Grep(options.from, options.to, readers, format, lineWriter{os.Stdout})
+	var dateError *dategrep.DateError
+	var readError *dategrep.ReadError
+	switch {
+	case errors.As(err, &dateError):
+		abortOnDateError(names[dateError.Input], 0, dateError.Err, dateError.Line)
+	case errors.Is(err, dategrep.ErrTooLong) && errors.As(err, &readError):
+		fatalln("Error reading", names[readError.Input], ": line longer than --buffer-size")
+	case errors.As(err, &readError):
+		fatalln("Error reading", names[readError.Input], ":", readError.Err)
+	case err != nil && !stopped:
+		fatalln("Error writing line:", err)
+	}
+}
+
+// lineWriter prints the lines written to it with writeLine and counts
+// them for the exit status.
+type lineWriter struct {
+	w io.Writer
+}
+
+// errStopped ends writing to a lineWriter once stopped is set.
+var errStopped = errors.New("stopped")
+
+func (l lineWriter) Write(p []byte) (int, error) {
+	writeLine(l.w, strings.TrimSuffix(string(p), "\n"))
+	if stopped {
+		return 0, errStopped
+	}
+	emitted++
+	return len(p), nil
+}
+
+func (i *Iterator) Print(to time.Time, options Options, format retime.Format) {
+	for !i.limitReached(options) {
+		i.Line, i.Err = i.readline()
+		if i.Err == io.EOF {
+			return
+		}
+		if i.Err != nil {
+			fatalln("Error reading", i.filename, ":", i.Err)
+		}
+		i.Time, i.Err = extract(i.Line, options, format)
+		if i.Err == nil && i.skewed(options, format) && options.excludeSkewed {
+			i.visible = false
+			continue
+		}
+		if i.Err == nil {
+			i.checkRegression(options)
+			i.key = secondaryKey(i.Line, options)
+		}
+
+		switch {
+		case i.Err == retime.ErrNoMatch && options.continues(i.Line):
+			i.emit(options)
+		case i.Err != nil && options.skips(i.Err):
+			if options.warnDateless {
+				warnDateError(i.filename, i.fileLineno(), i.Err, i.Line)
+			}
+			if options.datelessFile != nil {
+				writeLine(options.datelessFile, i.Line)
+			}
+			continue
 		case i.Err != nil:
-			log.Fatalln("Aborting. Found line without date:", i.Line)
+			abortOnDateError(i.filename, i.fileLineno(), i.Err, i.Line)
 		case i.Time.Before(to):
-			fmt.Println(i.Line)
+			i.visible = options.visible(i.Time)
+			i.emit(options)
 		default:
 			return
 		}
 	}
 }
 
+// emitted counts the lines printed over all files.
+var emitted int
+
+// counts holds the lines counted with --count per file.
+var counts = make(map[string]int)
+
+// printCounts prints the count of a single file or, like grep, the name
+// and count of each of several files.
+func printCounts(names []string) {
+	if len(names) == 1 {
+		writeLine(os.Stdout, strconv.Itoa(counts[names[0]]))
+		return
+	}
+	for _, name := range names {
+		writeLine(os.Stdout, name+":"+strconv.Itoa(counts[name]))
+	}
+}
+
+// matchStats summarizes the printed lines for --stats.
+type matchStats struct {
+	first, last time.Time
+	files       map[string]int
+}
+
+var stats = matchStats{files: make(map[string]int)}
+
+func (m *matchStats) add(filename string, dt time.Time) {
+	m.files[filename]++
+	if dt.IsZero() {
+		return
+	}
+	if m.first.IsZero() || dt.Before(m.first) {
+		m.first = dt
+	}
+	if dt.After(m.last) {
+		m.last = dt
+	}
+}
+
+// print writes the searched range, the range of the printed lines and
+// its span and the number of lines in total and of each file to w.
+func (m *matchStats) print(w io.Writer, names []string, options Options) {
+	bound := func(dt time.Time, unset string) string {
+		if dt.Equal(epoch) || dt.Equal(future) {
+			return unset
+		}
+		return dt.In(loc).Format(time.RFC3339Nano)
+	}
+	fmt.Fprintln(w, "Range:", bound(options.from, "start"), "-", bound(options.to, "end"))
+	if m.first.IsZero() {
+		fmt.Fprintln(w, "Matched: nothing")
+	} else {
+		fmt.Fprintln(w, "Matched:", bound(m.first, ""), "-", bound(m.last, ""))
+		fmt.Fprintln(w, "Span:", formatDuration(m.last.Sub(m.first), options))
+	}
+	fmt.Fprintln(w, "Lines:", emitted)
+	for _, name := range names {
+		fmt.Fprintln(w, name+":", m.files[name])
+	}
+}
+
+func (i *Iterator) emit(options Options) {
+	progress.update(i)
+	if i.visible && i.Err == nil {
+		i.visible = maxPer.allow(i, options)
+	}
+	if !i.visible {
+		return
+	}
+	if options.hasContext() && i.Err == nil {
+		separator.update(i, options)
+	}
+	text := i.Line
+	if options.outputLocation != nil && i.Err == nil {
+		text = convertZone(text, i.format, i.Time, options.outputLocation)
+	}
+	if options.color && i.Err == nil && !options.tsv {
+		text = highlight(text, i.format)
+	}
+	if i.Err != nil {
+		if options.datelessInheritTime && !i.previous.IsZero() {
+			text = i.previous.Format(time.RFC3339) + " " + text
+		}
+		text = options.datelessPrefix + text
+	}
+	var line string
+	switch {
+	case options.tsv:
+		var epoch string
+		if i.Err == nil {
+			epoch = strconv.FormatInt(i.Time.Unix(), 10)
+		}
+		line = epoch + "\t" + escapeTSV(i.filename) + "\t" + escapeTSV(text)
+	case options.byteOffset:
+		line = strconv.FormatInt(i.Offset, 10) + ":" + text
+	default:
+		line = text
+	}
+	if options.lineNumber && !options.tsv {
+		line = strconv.Itoa(i.Lineno) + ":" + line
+	}
+	if options.outputPrefix != "" && (i.Err == nil || options.prefixContinuation) {
+		line = i.expandPrefix(options.outputPrefix) + line
+	}
+	if options.withFilename && !options.tsv {
+		line = i.filename + ":" + line
+	}
+	if options.lastPer > 0 || options.coalesceJSON {
+		pending.add(i, line, options)
+		return
+	}
+	var dt time.Time
+	if i.Err == nil {
+		dt = i.Time
+	}
+	i.write(line, line, dt, false, options)
+}
+
+// convertZone replaces the first timestamp in line by dt in location.
+// Formats without layout like epoch are left alone.
+func convertZone(line string, format retime.Format, dt time.Time, location *time.Location) string {
+	span := format.Index(line)
+	if span == nil {
+		return line
+	}
+	ts, ok := format.Render(dt.In(location))
+	if !ok {
+		return line
+	}
+	return line[:span[0]] + ts + line[span[1]:]
+}
+
+// highlight colors the first timestamp in line.
+func highlight(line string, format retime.Format) string {
+	loc := format.Index(line)
+	if loc == nil {
+		return line
+	}
+	return line[:loc[0]] + "\x1b[1;32m" + line[loc[0]:loc[1]] + "\x1b[0m" + line[loc[1]:]
+}
+
+// write prints line or records it for --count or --endpoints. message
+// is sent to --webhook, it's line before --coalesce-multiline-into-json
+// encoded it. dt is the date of the line, zero for lines without date.
+// A marker like the one of --max-per-marker isn't a line of the input,
+// so it's only printed and kept in place with --reverse.
+func (i *Iterator) write(line, message string, dt time.Time, marker bool, options Options) {
+	if stopped {
+		return
+	}
+	if marker {
+		switch {
+		case options.quiet || options.count || options.endpoints:
+		case options.reverse:
+			reversed.push(reversedLine{out: i.out, text: line})
+		default:
+			writeLine(i.out, line)
+		}
+		return
+	}
+	i.printed++
+	if options.reverse && !options.quiet && !options.count && !options.endpoints {
+		// Lines without date are continuation lines, unless they were
+		// already joined to their record by pendingLine. The lines are
+		// counted once flush prints them, as --max-count keeps only the
+		// newest.
+		continuation := i.Err != nil && options.lastPer == 0 && !options.coalesceJSON
+		reversed.add(i.out, line, message, i.filename, dt, continuation)
+		return
+	}
+	countLine(i.filename, dt)
+	if options.quiet {
+		stopped = true
+		return
+	}
+	if options.count {
+		counts[i.filename]++
+		return
+	}
+	if options.endpoints {
+		i.endpoints.add(line)
+		allEndpoints.add(line)
+		return
+	}
+	sendHook(i.filename, dt, message)
+	writeLine(i.out, line)
+}
+
+// sendHook posts a printed line of filename dated dt to --webhook.
+func sendHook(filename string, dt time.Time, message string) {
+	if hook == nil || stopped {
+		return
+	}
+	r := jsonRecord{File: filename, Message: message}
+	if !dt.IsZero() {
+		r.Time = dt.Format(time.RFC3339Nano)
+	}
+	hook.send(r)
+}
+
+// countLine records a printed line of filename for the exit status and
+// --stats.
+func countLine(filename string, dt time.Time) {
+	emitted++
+	stats.add(filename, dt)
+}
+
+// stopped is set once nothing more is to be printed, because --quiet
+// found a line or the reader of the output is gone. The files aren't
+// read any further and run returns successfully.
+var stopped bool
+
+// writeLine prints line to w. Once the reader of a pipe like the pager
+// or head is gone, there's nobody left to read the remaining lines, so
+// dtgrep stops successfully. Writes to a closed stdout end the program
+// with SIGPIPE before, like other Unix tools.
+func writeLine(w io.Writer, line string) {
+	if stopped {
+		return
+	}
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		if errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrClosedPipe) {
+			stopped = true
+			return
+		}
+		fatalln("Error writing line:", err)
+	}
+}
+
+// expandPrefix replaces the placeholders of --output-prefix. Lines
+// without date have an empty {ts} and {epoch}.
+func (i *Iterator) expandPrefix(prefix string) string {
+	var ts, epoch string
+	if i.Err == nil {
+		ts = i.Time.Format(time.RFC3339)
+		epoch = strconv.FormatInt(i.Time.Unix(), 10)
+	}
+	return strings.NewReplacer(
+		"{file}", i.filename,
+		"{line}", strconv.Itoa(i.Lineno),
+		"{ts}", ts,
+		"{epoch}", epoch,
+	).Replace(prefix)
+}
+
+// endpoints keeps the first and last line printed with --endpoints.
+type endpoints struct {
+	first, last string
+	count       int
+}
+
+var allEndpoints endpoints
+
+func (e *endpoints) add(line string) {
+	if e.count == 0 {
+		e.first = line
+	}
+	e.last = line
+	e.count++
+}
+
+func (e *endpoints) print(w io.Writer) {
+	if e.count > 0 {
+		writeLine(w, e.first)
+	}
+	if e.count > 1 {
+		writeLine(w, e.last)
+	}
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// escapeTSV escapes backslashes, tabs and newlines with a backslash.
+func escapeTSV(s string) string {
+	return tsvEscaper.Replace(s)
+}
+
+// visible reports whether a line dated dt and its continuation lines
+// should be printed.
+func (o Options) visible(dt time.Time) bool {
+	if o.invert && !dt.Before(o.excludeFrom) && dt.Before(o.excludeTo) {
+		return false
+	}
+	if !o.window.Contains(dt) {
+		return false
+	}
+	if o.onePer > 0 {
+		bucket := bucketOf(dt, o.onePer)
+		if o.buckets[bucket] {
+			return false
+		}
+		o.buckets[bucket] = true
+	}
+	return true
+}
+
+// bucketOf returns the start of the interval containing dt in seconds.
+func bucketOf(dt time.Time, interval time.Duration) int64 {
+	return truncateWall(dt, interval).Unix()
+}
+
+// truncateWall rounds dt down to a multiple of d aligned to the wall
+// clock of the location.
+func truncateWall(dt time.Time, d time.Duration) time.Time {
+	_, offset := dt.In(loc).Zone()
+	shift := time.Duration(offset) * time.Second
+	return dt.Add(shift).Truncate(d).Add(-shift)
+}
+
+// bucketLimit drops dated lines beyond the first limit lines in each
+// interval for --max-per. As lines are printed in order of their dates,
+// only the current interval has to be counted.
+type bucketLimit struct {
+	interval time.Duration
+	limit    int
+	marker   bool
+
+	bucket         int64
+	count, dropped int
+
+	// iterator and options are those of the last allowed line, the
+	// marker is written with them.
+	iterator *Iterator
+	options  Options
+}
+
+var maxPer bucketLimit
+
+// hook receives all printed lines if --webhook is given.
+var hook *webhook
+
+// Set parses a limit like "1m:10".
+func (b *bucketLimit) Set(spec string) error {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 {
+		return errors.New("must be of the form INTERVAL:K")
+	}
+	interval, err := time.ParseDuration(parts[0])
+	if err != nil {
+		return err
+	}
+	limit, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+	if interval <= 0 || limit < 1 {
+		return errors.New("interval and count must be positive")
+	}
+	b.interval, b.limit = interval, limit
+	return nil
+}
+
+func (b *bucketLimit) String() string {
+	if b.interval == 0 {
+		return ""
+	}
+	return b.interval.String() + ":" + strconv.Itoa(b.limit)
+}
+
+// allow reports whether the dated line of i is within the limit of its
+// interval.
+func (b *bucketLimit) allow(i *Iterator, options Options) bool {
+	if b.interval == 0 {
+		return true
+	}
+	bucket := bucketOf(i.Time, b.interval)
+	if b.count == 0 || bucket != b.bucket {
+		b.flush()
+		b.bucket, b.count = bucket, 0
+	}
+	b.count++
+	if b.count > b.limit {
+		b.dropped++
+		return false
+	}
+	b.iterator, b.options = i, options
+	return true
+}
+
+// flush prints the marker for the lines dropped in the current interval.
+func (b *bucketLimit) flush() {
+	if b.marker && b.dropped > 0 {
+		b.iterator.write(fmt.Sprintf("... (%d more in this bucket) ...", b.dropped), "", time.Time{}, true, b.options)
+	}
+	b.dropped = 0
+}
+
+// contextSeparator prints "--" between the context lines before the
+// hits, the hits and the context lines after them.
+type contextSeparator struct {
+	region  int
+	started bool
+}
+
+var separator contextSeparator
+
+// update prints the separator if the dated line of i is in another
+// region than the line printed before.
+func (c *contextSeparator) update(i *Iterator, options Options) {
+	var region int
+	switch {
+	case i.Time.Before(options.hitsFrom):
+		region = -1
+	case !i.Time.Before(options.hitsTo):
+		region = 1
+	}
+	if c.started && region != c.region {
+		if options.reverse {
+			reversed.push(reversedLine{out: i.out, text: "--"})
+		} else {
+			writeLine(i.out, "--")
+		}
+	}
+	c.region, c.started = region, true
+}
+
+// reverseBuffer collects the lines printed with --reverse. If max is
+// positive, only the newest max lines are printed, separators don't
+// count. Once the lines kept in memory exceed limit bytes, they are
+// spilled to disk.
+type reverseBuffer struct {
+	lines []reversedLine
+	max   int
+	count int
+
+	limit, size int
+	spilled     *spillFile
+	outs        []io.Writer
+}
+
+// reversedLine is a line with its continuation lines, lines in total.
+// The date is the one of the first line. Separators and markers have no lines.
+type reversedLine struct {
+	out     io.Writer
+	text    string
+	message string
+	file    string
+	time    time.Time
+	lines   int
+}
+
+// spilledLine is a reversedLine on disk, with the index of its output
+// in outs.
+type spilledLine struct {
+	Out     int
+	Text    string
+	Message string
+	File    string
+	Time    time.Time
+	Lines   int
+}
+
+var reversed reverseBuffer
+
+// add keeps line of filename dated dt for out and message for
+// --webhook. Continuation lines are kept together with the line before
+// them, so records stay readable.
+func (r *reverseBuffer) add(out io.Writer, line, message, filename string, dt time.Time, continuation bool) {
+	if continuation && len(r.lines) > 0 {
+		last := &r.lines[len(r.lines)-1]
+		last.text += "\n" + line
+		last.message += "\n" + message
+		last.lines++
+		r.size += len(line) + 1
+		return
+	}
+	r.push(reversedLine{out, line, message, filename, dt, 1})
+}
+
+// push keeps l as the newest line.
+func (r *reverseBuffer) push(l reversedLine) {
+	if r.limit > 0 && r.size > r.limit {
+		r.spill()
+	}
+	r.lines = append(r.lines, l)
+	r.size += len(l.text)
+	if l.lines > 0 {
+		r.count++
+	}
+	if r.max > 0 && r.count > r.max {
+		r.count--
+		r.size -= len(r.lines[0].text)
+		r.lines = r.lines[1:]
+		// A separator left before the oldest line goes with it.
+		for len(r.lines) > 0 && r.lines[0].lines == 0 {
+			r.size -= len(r.lines[0].text)
+			r.lines = r.lines[1:]
+		}
+	}
+}
+
+// spill writes the lines in memory as a run to disk.
+func (r *reverseBuffer) spill() {
+	var err error
+	if r.spilled == nil {
+		r.spilled, err = newSpillFile()
+		if err != nil {
+			fatalln("Cannot spill lines to disk:", err)
+		}
+	}
+	for _, l := range r.lines {
+		out := 0
+		for out < len(r.outs) && r.outs[out] != l.out {
+			out++
+		}
+		if out == len(r.outs) {
+			r.outs = append(r.outs, l.out)
+		}
+		if err = r.spilled.add(spilledLine{out, l.text, l.message, l.file, l.time, l.lines}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = r.spilled.endRun()
+	}
+	if err != nil {
+		fatalln("Cannot spill lines to disk:", err)
+	}
+	r.lines, r.size, r.count = nil, 0, 0
+}
+
+// flush prints the kept lines newest first, starting with those in
+// memory and then the runs on disk from the last one.
+func (r *reverseBuffer) flush() {
+	printed := 0
+	print := func(out io.Writer, l spilledLine) {
+		if r.max > 0 && printed >= r.max {
+			return
+		}
+		if l.Lines > 0 {
+			sendHook(l.File, l.Time, l.Message)
+			printed++
+		}
+		writeLine(out, l.Text)
+		for k := 0; k < l.Lines; k++ {
+			dt := l.Time
+			if k > 0 {
+				dt = time.Time{}
+			}
+			countLine(l.File, dt)
+		}
+	}
+	for k := len(r.lines) - 1; k >= 0; k-- {
+		l := r.lines[k]
+		print(l.out, spilledLine{Text: l.text, Message: l.message, File: l.file, Time: l.time, Lines: l.lines})
+	}
+	r.lines = nil
+	if r.spilled == nil {
+		return
+	}
+	defer r.spilled.remove()
+	for k := r.spilled.runs() - 1; k >= 0; k-- {
+		var run []spilledLine
+		dec := r.spilled.run(k)
+		for {
+			var l spilledLine
+			err := dec.Decode(&l)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				fatalln("Cannot read spilled lines:", err)
+			}
+			run = append(run, l)
+		}
+		for k := len(run) - 1; k >= 0; k-- {
+			print(r.outs[run[k].Out], run[k])
+		}
+	}
+}
+
+// pendingLine holds a dated line and its continuation lines until the
+// next dated line shows whether it has to be printed. With --last-per it
+// is replaced by lines of the same interval.
+type pendingLine struct {
+	iterator *Iterator
+	line     string
+	time     time.Time
+	bucket   int64
+	options  Options
+}
+
+var pending pendingLine
+
+// add keeps line as pending and prints the previous pending line unless
+// it belongs to the same --last-per interval. Lines without date belong
+// to the line before them.
+func (p *pendingLine) add(i *Iterator, line string, options Options) {
+	if i.Err != nil {
+		if p.iterator != nil {
+			p.line += "\n" + line
+		}
+		return
+	}
+	var bucket int64
+	if options.lastPer > 0 {
+		bucket = bucketOf(i.Time, options.lastPer)
+	}
+	if p.iterator != nil && (options.lastPer == 0 || p.bucket != bucket) {
+		p.flush()
+	}
+	*p = pendingLine{iterator: i, line: line, time: i.Time, bucket: bucket, options: options}
+}
+
+// jsonRecord is a line with its continuation lines printed with
+// --coalesce-multiline-into-json or a line sent to --webhook.
+type jsonRecord struct {
+	File    string `json:"file"`
+	Time    string `json:"time"`
+	Message string `json:"message"`
+}
+
+func (p *pendingLine) flush() {
+	if p.iterator == nil {
+		return
+	}
+	line := p.line
+	if p.options.coalesceJSON {
+		var b strings.Builder
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		err := enc.Encode(jsonRecord{
+			File:    p.iterator.filename,
+			Time:    p.time.Format(time.RFC3339Nano),
+			Message: p.line,
+		})
+		if err != nil {
+			fatalln("Error encoding line:", err)
+		}
+		line = strings.TrimSuffix(b.String(), "\n")
+	}
+	p.iterator.write(line, p.line, p.time, false, p.options)
+	p.iterator = nil
+}
+
+// limitReached reports whether --merge-limit or --max-count over all
+// files stop printing, or printing stopped altogether.
+func (o Options) limitReached() bool {
+	return stopped || o.mergeLimit > 0 && emitted >= o.mergeLimit ||
+		o.maxCount > 0 && !o.maxCountPerFile && !o.reverse && emitted >= o.maxCount
+}
+
+// limitReached reports whether no more lines of i may be printed.
+func (i *Iterator) limitReached(options Options) bool {
+	return options.limitReached() ||
+		options.maxCountPerFile && options.maxCount > 0 && i.printed >= options.maxCount
+}
+
+var offsetRegexp = regexp.MustCompile(`^([+-])(\d\d):?(\d\d)$`)
+
+// loadLocation returns the location with the given IANA name or a fixed
+// zone for offsets like +02:00, +0200 and Z.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "Z" {
+		return time.FixedZone("Z", 0), nil
+	}
+	match := offsetRegexp.FindStringSubmatch(name)
+	if match == nil {
+		return time.LoadLocation(name)
+	}
+	hours, _ := strconv.Atoi(match[2])
+	minutes, _ := strconv.Atoi(match[3])
+	if hours > 23 || minutes > 59 {
+		return nil, errors.New("invalid offset " + name)
+	}
+	offset := hours*3600 + minutes*60
+	if match[1] == "-" {
+		offset = -offset
+	}
+	return time.FixedZone(name, offset), nil
+}
+
+// open is used by openFile to open files.
+var open = os.Open
+
+// openFile opens filename and retries --retry-open times on transient
+// errors, doubling --retry-delay after each attempt.
+func openFile(filename string, options Options) (*os.File, error) {
+	delay := options.retryDelay
+	for n := 0; ; n++ {
+		file, err := open(filename)
+		if err == nil || n >= options.retryOpen || !isTransient(err) {
+			return file, err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// isRegular reports whether f is a regular file. Pipes and devices can't
+// be searched by seeking and have to be read like stdin.
+func isRegular(f *os.File) bool {
+	fileInfo, err := f.Stat()
+	return err == nil && fileInfo.Mode().IsRegular()
+}
+
+func isTransient(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EAGAIN, syscall.EINTR, syscall.ETIMEDOUT, syscall.ECONNRESET} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// lazyFile creates the file name on the first write, so inputs without
+// printed lines leave no empty file behind.
+type lazyFile struct {
+	name string
+	file *os.File
+}
+
+func (l *lazyFile) Write(p []byte) (int, error) {
+	if l.file == nil {
+		file, err := os.Create(l.name)
+		if err != nil {
+			fatalln("Cannot create", l.name, ":", err)
+		}
+		l.file = file
+	}
+	return l.file.Write(p)
+}
+
+func (l *lazyFile) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// splitOutputNames returns a file in dir for every input file, named
+// after its base name with the suffix .matched. Clashing base names get a
+// number added.
+func splitOutputNames(dir string, files []string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		base := filepath.Base(file)
+		if file == "-" {
+			base = "stdin"
+		}
+		name := base + ".matched"
+		for n := 2; seen[name]; n++ {
+			name = base + "." + strconv.Itoa(n) + ".matched"
+		}
+		seen[name] = true
+		names = append(names, filepath.Join(dir, name))
+	}
+	return names
+}
+
+// expandGlobs replaces arguments containing glob patterns by the files
+// they match, as not every shell does that. Arguments naming an existing
+// file are kept, patterns matching nothing are an error.
+func expandGlobs(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		if isURL(arg) || !strings.ContainsAny(arg, "*?[") {
+			files = append(files, arg)
+			continue
+		}
+		if _, err := os.Stat(arg); err == nil {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, errors.New("malformed pattern " + arg)
+		}
+		if len(matches) == 0 {
+			return nil, errors.New("no files match " + arg)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// expandDirs replaces directories in args by the regular files below
+// them in lexical order.
+func expandDirs(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		if fileInfo, err := os.Stat(arg); err != nil || !fileInfo.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// readFileList returns the file names listed in filename, or stdin if it
+// is "-", one per line. Empty lines are skipped.
+func readFileList(filename string) ([]string, error) {
+	r := os.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if name := strings.TrimRight(scanner.Text(), "\r"); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, scanner.Err()
+}
+
+// lastDate returns the latest date in filename. Uncompressed files are
+// searched backwards from their end, all others are read completely.
+func lastDate(filename string, options Options, format retime.Format) (time.Time, error) {
+	var last time.Time
+
+	if isURL(filename) {
+		return last, errors.New("can't read URLs twice")
+	}
+
+	file, err := openFile(filename, options)
+	if err != nil {
+		return last, err
+	}
+	defer file.Close()
+
+	c, ok := compressions[path.Ext(filename)]
+	if !ok {
+		return findLastSeekable(file, options, format)
+	}
+	r, err := c.newReader(file)
+	if err != nil {
+		return last, err
+	}
+
+	i := newIterator(filename, r, 0)
+	i.recordLines, i.timestampLine = options.recordLines, options.timestampLine
+	for {
+		line, err := i.readline()
+		if err == io.EOF {
+			return last, nil
+		}
+		if err != nil {
+			return last, err
+		}
+		dt, err := extract(line, options, format)
+		if err == nil && dt.After(last) {
+			last = dt
+		}
+	}
+}
+
+// findLastSeekable returns the date of the last dated line in f. It reads
+// blocks of growing size from the end until a date is found.
+func findLastSeekable(f *os.File, options Options, format retime.Format) (time.Time, error) {
+	var last time.Time
+
+	fileInfo, err := f.Stat()
+	if err != nil {
+		return last, err
+	}
+	size := fileInfo.Size()
+
+	for blockSize := int64(4096); ; blockSize *= 2 {
+		start := size - blockSize
+		if start < 0 || options.recordLines > 1 {
+			start = 0
+		}
+		_, err = f.Seek(start, os.SEEK_SET)
+		if err != nil {
+			return last, err
+		}
+		i := newIterator(f.Name(), f, start)
+		i.recordLines, i.timestampLine = options.recordLines, options.timestampLine
+		if start > 0 {
+			i.readline() // skip partial line
+		}
+		for {
+			line, err := i.readline()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return last, err
+			}
+			dt, err := extract(line, options, format)
+			if err == nil {
+				last = dt
+			}
+		}
+		if !last.IsZero() || start == 0 {
+			return last, nil
+		}
+	}
+}
+
+// firstDate returns the date of the first dated line read from r.
+func firstDate(r io.Reader, options Options, format retime.Format) (time.Time, error) {
+	var first time.Time
+
+	i := newIterator("", r, 0)
+	for {
+		line, err := i.readline()
+		if err == io.EOF {
+			return first, nil
+		}
+		if err != nil {
+			return first, err
+		}
+		if dt, err := extract(line, options, format); err == nil {
+			return dt, nil
+		}
+	}
+}
+
+// outsideRange reports whether the first and last date of f are both
+// before or both after the range. In sorted files, all lines are then
+// outside of it.
+func outsideRange(f *os.File, options Options, format retime.Format) (bool, error) {
+	if _, err := f.Seek(0, os.SEEK_SET); err != nil {
+		return false, err
+	}
+	first, err := firstDate(f, options, format)
+	if err != nil || first.IsZero() {
+		return false, err
+	}
+	last, err := findLastSeekable(f, options, format)
+	if err != nil {
+		return false, err
+	}
+	return outside(first, last, options), nil
+}
+
+// outside reports whether first and last are both before or both after
+// the range. A zero last is unknown.
+func outside(first, last time.Time, options Options) bool {
+	if !last.IsZero() && last.Before(options.from) {
+		return true
+	}
+	// Files after now are still searched to warn about them.
+	return !first.Before(options.to) && !(options.warnFuture && first.After(now))
+}
+
+// checkpoint is the position after the last line processed, saved with
+// --checkpoint to resume from there.
+type checkpoint struct {
+	File   string    `json:"file"`
+	Offset int64     `json:"offset"`
+	Time   time.Time `json:"time"`
+
+	path  string
+	count int
+
+	// saved is the count at the last save.
+	saved int
+}
+
+// checkpointInterval is the number of lines after which the checkpoint
+// is saved.
+const checkpointInterval = 1000
+
+var progress checkpoint
+
+func (c *checkpoint) update(i *Iterator) {
+	if c.path == "" {
+		return
+	}
+	c.File, c.Offset = i.filename, i.pos
+	if i.Err == nil {
+		c.Time = i.Time
+	}
+	c.count++
+	if c.count%checkpointInterval == 0 {
+		c.save()
+	}
+}
+
+// save writes the checkpoint to a temporary file first and renames it,
+// so a killed process leaves either the old or the new checkpoint.
+func (c *checkpoint) save() {
+	if c.path == "" || c.count == c.saved {
+		return
+	}
+	c.saved = c.count
+	b, err := json.Marshal(c)
+	if err != nil {
+		fatalln("Error encoding checkpoint:", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(b, '\n'), 0644); err != nil {
+		fatalln("Cannot write checkpoint", tmp, ":", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		fatalln("Cannot write checkpoint", c.path, ":", err)
+	}
+}
+
+// loadCheckpoint returns the offset saved in path for filename. It
+// returns false if there's no checkpoint for filename or filename is
+// shorter than the offset, as after a log rotation.
+func loadCheckpoint(path, filename string) (int64, bool) {
+	if path == "" {
+		return 0, false
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, false
+	}
+	if err != nil {
+		fatalln("Cannot read checkpoint", path, ":", err)
+	}
+	var c checkpoint
+	if err := json.Unmarshal(b, &c); err != nil {
+		fatalln("Cannot read checkpoint", path, ":", err)
+	}
+	if c.File != filename {
+		return 0, false
+	}
+	fileInfo, err := os.Stat(filename)
+	if err != nil || fileInfo.Size() < c.Offset {
+		return 0, false
+	}
+	return c.Offset, true
+}
+
+// compression describes a compressed file format. Compressed files can't
+// be searched by bisection and are always read from the start.
+type compression struct {
+	name      string
+	newReader func(io.Reader) (io.Reader, error)
+}
+
+var (
+	gzipCompression = compression{"gzip", func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	}}
+	bzip2Compression = compression{"bzip2", func(r io.Reader) (io.Reader, error) {
+		return bzip2.NewReader(r), nil
+	}}
+	xzCompression = compression{"xz", func(r io.Reader) (io.Reader, error) {
+		return xz.NewReader(r)
+	}}
+	lzwCompression = compression{"compress", func(r io.Reader) (io.Reader, error) {
+		return ncompress.NewReader(r)
+	}}
+)
+
+// compressions maps file extensions to their compression. The case
+// matters: .z is an old extension of gzip, while .Z is used by compress
+// with its own format.
+var compressions = map[string]compression{
+	".gz":  gzipCompression,
+	".z":   gzipCompression,
+	".Z":   lzwCompression,
+	".bz2": bzip2Compression,
+	".bz":  bzip2Compression,
+	".xz":  xzCompression,
+}
+
+// fileType returns how filename is read: stdin, the name of its
+// compression or plain.
+func fileType(filename string) string {
+	if filename == "-" {
+		return "stdin"
+	}
+	if isURL(filename) {
+		return "url"
+	}
+	if c, ok := compressions[path.Ext(filename)]; ok {
+		return c.name
+	}
+	return "plain"
+}
+
+// printSlice prints all lines starting between options.fromPercent and
+// options.toPercent of the file size and reports the dates they span.
+func printSlice(filename string, options Options, format retime.Format) {
+	if fileType(filename) != "plain" {
+		fatalln("Can't slice", filename, ": file is not seekable")
+	}
+	file, err := openFile(filename, options)
+	if err != nil {
+		fatalln("Cannot open", filename, ":", err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		fatalln("Cannot open", filename, ":", err)
+	}
+	size := float64(fileInfo.Size())
+	start := int64(size * options.fromPercent / 100)
+	end := int64(size * options.toPercent / 100)
+
+	// Start one byte early, so a line beginning exactly at start isn't
+	// skipped as partial line.
+	if start > 0 {
+		start--
+	}
+	_, err = file.Seek(start, os.SEEK_SET)
+	if err != nil {
+		fatalln("Cannot seek in", filename, ":", err)
+	}
+	i := newIterator(filename, file, start)
+	i.visible = true
+	i.format = format
+	if start > 0 {
+		i.readline() // skip partial line
+	}
+
+	var first, last time.Time
+	for !stopped {
+		i.Line, i.Err = i.readline()
+		if i.Err == io.EOF || i.Offset >= end {
+			break
+		}
+		if i.Err != nil {
+			fatalln("Error reading", filename, ":", i.Err)
+		}
+		i.Time, i.Err = extract(i.Line, options, format)
+		i.emit(options)
+		if i.Err != nil {
+			continue
+		}
+		last = i.Time
+		if first.IsZero() {
+			first = last
+		}
+	}
+
+	if first.IsZero() {
+		log.Println(filename, ": slice contains no dates")
+	} else {
+		log.Println(filename, ": slice spans", first.Format(time.RFC3339), "to", last.Format(time.RFC3339))
+	}
+}
+
+// secondaryKey returns the number captured by --secondary-key in line
+// from the group named key, the first group or the whole match. Lines
+// without a number have the key 0.
+func secondaryKey(line string, options Options) float64 {
+	re := options.secondaryKey
+	if re == nil {
+		return 0
+	}
+	match := re.FindStringSubmatch(line)
+	if match == nil {
+		return 0
+	}
+	group := 0
+	if k := re.SubexpIndex("key"); k > 0 {
+		group = k
+	} else if re.NumSubexp() > 0 {
+		group = 1
+	}
+	key, _ := strconv.ParseFloat(match[group], 64)
+	return key
+}
+
+// extract returns the date of line, filling in the year if the format
+// doesn't contain one.
+func extract(line string, options Options, format retime.Format) (time.Time, error) {
+	if options.recordLines > 1 {
+		line = strings.Split(line, "\n")[options.timestampLine-1]
+	}
+	if options.csv {
+		r := csv.NewReader(strings.NewReader(line))
+		r.FieldsPerRecord = -1
+		record, err := r.Read()
+		if err != nil || len(record) < options.csvField {
+			return time.Time{}, retime.ErrNoMatch
+		}
+		line = record[options.csvField-1]
+	}
+	if options.normalizeWhitespace {
+		line = strings.Join(strings.Fields(line), " ")
+	}
+	dt, err := format.Extract(line)
+	if !format.HasYear() && options.year != 0 {
+		dt = dt.AddDate(options.year, 0, 0)
+	} else if !format.HasYear() {
+		dt = fixtime.AddYear(dt, now)
+	}
+	return dt, err
+}
+
+// fatalMu is never unlocked, so only the first of several goroutines
+// failing at once reports its error before exiting.
+var fatalMu sync.Mutex
+
+// tempFiles are removed by exit, as os.Exit skips the deferred
+// removals.
+var tempFiles []string
+
+// exit removes tempFiles and exits with status.
+func exit(status int) {
+	for _, name := range tempFiles {
+		os.Remove(name)
+	}
+	if collectErrors {
+		writeCollectedErrors()
+	}
+	os.Exit(status)
+}
+
+// fatalln is like log.Fatalln, but exits with status 2.
+func fatalln(v ...interface{}) {
+	fatalMu.Lock()
+	log.Println(v...)
+	exit(2)
+}
+
+// fatalf is like log.Fatalf, but exits with status 2.
+func fatalf(format string, v ...interface{}) {
+	fatalMu.Lock()
+	log.Printf(format, v...)
+	exit(2)
+}
+
+// warnDateError reports a line skipped with --on-error warn. lineno is
+// 0 if the number of the line is unknown.
+func warnDateError(filename string, lineno int, err error, line string) {
+	warning := "Warning: Skipped line with malformed date"
+	if err == retime.ErrNoMatch {
+		warning = "Warning: Skipped line without date"
+	}
+	if errorFormat == "json" {
+		writeErrorRecord(errorRecord{File: filename, Line: lineno, Text: line, Error: warning})
+		return
+	}
+	log.Println(warning, "in", filename+":", line)
+}
+
+func abortOnDateError(filename string, lineno int, err error, line string) {
+	if errorFormat == "json" {
+		fatalMu.Lock()
+		writeErrorRecord(errorRecord{File: filename, Line: lineno, Text: line, Error: err.Error()})
+		exit(2)
+	}
+	if err == retime.ErrNoMatch {
+		fatalln("Aborting. Found line without date:", line)
+	}
+	fatalln("Aborting. Found line with malformed date:", line)
+}
+
+// errorFormat is either "text" or "json".
+var errorFormat = "text"
+
+// collectErrors keeps the error records in collectedErrors until exit.
+var collectErrors bool
+
+var (
+	collectedErrors   = []errorRecord{}
+	collectedErrorsMu sync.Mutex
+)
+
+type errorRecord struct {
+	File  string `json:"file,omitempty"`
+	Line  int    `json:"line,omitempty"`
+	Text  string `json:"text,omitempty"`
+	Error string `json:"error"`
+}
+
+func writeErrorRecord(r errorRecord) {
+	if collectErrors {
+		collectedErrorsMu.Lock()
+		collectedErrors = append(collectedErrors, r)
+		collectedErrorsMu.Unlock()
+		return
+	}
+	json.NewEncoder(os.Stderr).Encode(r)
+}
+
+// writeCollectedErrors prints the records kept with --collect-errors as
+// a JSON array.
+func writeCollectedErrors() {
+	collectedErrorsMu.Lock()
+	defer collectedErrorsMu.Unlock()
+	json.NewEncoder(os.Stderr).Encode(collectedErrors)
+}
+
+// jsonLog turns every message written by the log package into an
+// errorRecord.
+type jsonLog struct{}
+
+func (jsonLog) Write(p []byte) (int, error) {
+	writeErrorRecord(errorRecord{Error: strings.TrimSuffix(string(p), "\n")})
+	return len(p), nil
+}
+
+// fileLineno returns the number of Line in its file or 0 if reading
+// didn't start at the beginning of the file, like after a binary search.
+func (i *Iterator) fileLineno() int {
+	if i.start != 0 {
+		return 0
+	}
+	return i.Lineno
+}
+
+// aheadLine is a line read ahead of Line with the positions to restore
+// when it is returned by readline.
+type aheadLine struct {
+	text          string
+	err           error
+	offset, pos   int64
+	lineno, lines int
+}
+
+// readline returns the next line or, with --record-lines, the next record
+// joined by newlines.
+func (i *Iterator) readline() (string, error) {
+	if len(i.ahead) > 0 {
+		l := i.ahead[0]
+		i.ahead = i.ahead[1:]
+		i.Offset, i.pos, i.Lineno, i.lines = l.offset, l.pos, l.lineno, l.lines
+		return l.text, l.err
+	}
+	line, err := i.readRecord()
+	i.pos = i.read
+	return line, err
+}
+
+// readRecord reads the next line or record from the scanner.
+func (i *Iterator) readRecord() (string, error) {
+	i.Offset = i.read
+	i.Lineno = i.lines + 1
+	if i.recordLines <= 1 {
+		line, err := readline(i.Scanner)
+		if err == nil {
+			i.lines++
+		}
+		return line, err
+	}
+	var lines []string
+	for len(lines) < i.recordLines {
+		line, err := readline(i.Scanner)
+		if err == io.EOF && len(lines) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		i.lines++
+		lines = append(lines, line)
+	}
+	if len(lines) < i.timestampLine {
+		log.Println("Ignoring incomplete record at end of", i.filename)
+		return "", io.EOF
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// bufferSize is the length of the longest line that can be read.
+var bufferSize = 1024 * 1024
+
+// newScanner returns a scanner for r whose buffer grows up to
+// bufferSize.
+func newScanner(r io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(r)
+	size := bufio.MaxScanTokenSize
+	if bufferSize < size {
+		size = bufferSize
+	}
+	s.Buffer(make([]byte, size), bufferSize)
+	return s
+}
+
 func readline(s *bufio.Scanner) (string, error) {
 	ret := s.Scan()
 	if !ret && s.Err() == nil {
 		return "", io.EOF
 	}
+	if s.Err() == bufio.ErrTooLong {
+		return "", errors.New("line longer than --buffer-size")
+	}
 	if !ret {
 		return "", s.Err()
 	}
 	return s.Text(), nil
 }
 
-func (i *Iterator) Scan(from, to time.Time, ignoreError bool, format retime.Format) {
+// formatDuration returns d as "1h23m45.6s" or, with --human, as "1 hour
+// 23 minutes".
+func formatDuration(d time.Duration, options Options) string {
+	if options.human {
+		return humanizeDuration(d)
+	}
+	return d.String()
+}
+
+// humanizeDuration returns d in its two largest units, like "3 days 4
+// hours" or "1 minute 30 seconds". Durations below a second are given in
+// milliseconds.
+func humanizeDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	unit := func(n int64, name string) string {
+		if n == 1 {
+			return "1 " + name
+		}
+		return strconv.FormatInt(n, 10) + " " + name + "s"
+	}
+	d = d.Round(time.Millisecond)
+	if d < time.Second {
+		return sign + unit(int64(d/time.Millisecond), "millisecond")
+	}
+
+	units := []struct {
+		size time.Duration
+		name string
+	}{
+		{24 * time.Hour, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+		{time.Second, "second"},
+	}
+	for i, u := range units {
+		if i == len(units)-1 {
+			return sign + unit(int64(d.Round(time.Second)/time.Second), u.name)
+		}
+		// Round before comparing, so 59.6 seconds becomes a minute.
+		next := units[i+1]
+		rounded := d.Round(next.size)
+		if rounded < u.size {
+			continue
+		}
+		d = rounded
+		s := unit(int64(d/u.size), u.name)
+		if rest := int64(d % u.size / next.size); rest > 0 {
+			s += " " + unit(rest, next.name)
+		}
+		return sign + s
+	}
+	return ""
+}
+
+// checkRegression warns if the current line is dated before the
+// previous one or aborts with --strict-sorted if it isn't dated after it.
+func (i *Iterator) checkRegression(options Options) {
+	if options.strictSorted && !i.previous.IsZero() && !i.Time.After(i.previous) {
+		fatalf("Aborting. Timestamps not increasing in %s line %d: %s after %s\n",
+			i.filename, i.Lineno, i.Time.Format(time.RFC3339Nano), i.previous.Format(time.RFC3339Nano))
+	}
+	if options.warnRegression && i.Time.Before(i.previous) {
+		log.Printf("Warning: Clock regression in %s line %d: %s before previous line\n",
+			i.filename, i.Lineno, formatDuration(i.previous.Sub(i.Time), options))
+	}
+	i.previous = i.Time
+}
+
+// skewed reports whether the date of the line is more than --max-skew
+// away from the last accepted date as well as from the dates of the next
+// two dated lines and warns about it. After a jump of the clock the
+// following lines are close, so the line is accepted.
+func (i *Iterator) skewed(options Options, format retime.Format) bool {
+	if options.maxSkew == 0 {
+		return false
+	}
+	neighbours, from := i.nextDates(2, options, format), "next line"
+	if !i.reference.IsZero() {
+		neighbours, from = append([]time.Time{i.reference}, neighbours...), "previous line"
+	}
+	for _, dt := range neighbours {
+		if distance(i.Time, dt) <= options.maxSkew {
+			i.reference = i.Time
+			return false
+		}
+	}
+	if len(neighbours) == 0 {
+		i.reference = i.Time
+		return false
+	}
+	log.Printf("Warning: Suspect date in %s line %d: %s away from %s\n",
+		i.filename, i.Lineno, formatDuration(distance(i.Time, neighbours[0]), options), from)
+	return true
+}
+
+// distance returns the absolute difference between a and b.
+func distance(a, b time.Time) time.Duration {
+	d := a.Sub(b)
+	if d < 0 {
+		d = -d
+	}
+	return d
+}
+
+// nextDates returns the dates of up to n dated lines after Line. The
+// lines are kept to be returned by readline later.
+func (i *Iterator) nextDates(n int, options Options, format retime.Format) []time.Time {
+	var dates []time.Time
+	offset, pos, lineno, lines := i.Offset, i.pos, i.Lineno, i.lines
+	for k := 0; len(dates) < n; k++ {
+		if k == len(i.ahead) {
+			if k > 0 {
+				i.lines = i.ahead[k-1].lines
+			}
+			text, err := i.readRecord()
+			i.ahead = append(i.ahead, aheadLine{text, err, i.Offset, i.read, i.Lineno, i.lines})
+		}
+		l := i.ahead[k]
+		if l.err != nil {
+			break
+		}
+		if dt, err := extract(l.text, options, format); err == nil {
+			dates = append(dates, dt)
+		}
+	}
+	i.Offset, i.pos, i.Lineno, i.lines = offset, pos, lineno, lines
+	return dates
+}
+
+func (i *Iterator) Scan(options Options, format retime.Format) {
 	for {
-		i.Line, i.Err = readline(i.Scanner)
-		if i.Err != nil {
+		i.Line, i.Err = i.readline()
+		if i.Err == io.EOF {
 			break
 		}
-		i.Time, i.Err = format.Extract(i.Line)
-		i.Time = fixtime.AddYear(i.Time, now)
-		if i.Err != nil && ignoreError {
+		if i.Err != nil {
+			fatalln("Error reading", i.filename, ":", i.Err)
+		}
+		i.Time, i.Err = extract(i.Line, options, format)
+		if i.Err == retime.ErrNoMatch && options.continues(i.Line) || i.Err != nil && options.skipHeader && !i.dated {
+			continue
+		}
+		if i.Err != nil && options.skips(i.Err) {
+			if options.warnDateless {
+				warnDateError(i.filename, i.fileLineno(), i.Err, i.Line)
+			}
 			continue
 		}
 		if i.Err != nil {
-			log.Fatalln("Aborting. Found line without date:", i.Line)
+			abortOnDateError(i.filename, i.fileLineno(), i.Err, i.Line)
+		}
+		if i.skewed(options, format) && options.excludeSkewed {
+			continue
 		}
-		if i.Time.After(to) {
+		i.dated = true
+		i.checkRegression(options)
+		i.key = secondaryKey(i.Line, options)
+		if i.Time.After(options.to) {
 			i.Err = io.EOF
 			break
 		}
-		if i.Time.Equal(from) || i.Time.After(from) {
+		if i.Time.Equal(options.from) || i.Time.After(options.from) {
 			break
 		}
 	}
 }
 
-func findStartSeekable(f *os.File, options Options, format retime.Format) (*bufio.Scanner, error) {
+// probeDate returns the date of the first dated line starting after
+// offset in f.
+func probeDate(f *os.File, offset int64, options Options, format retime.Format) (time.Time, error) {
+	if _, err := f.Seek(offset, os.SEEK_SET); err != nil {
+		return time.Time{}, err
+	}
+	scanner := newScanner(f)
+
+	if offset > 0 {
+		_, err := readline(scanner) // skip partial line
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	for {
+		line, err := readline(scanner)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		dt, err := extract(line, options, format)
+		if err != nil && options.passes(line, err) {
+			continue
+		}
+		if err != nil {
+			abortOnDateError(f.Name(), 0, err, line)
+		}
+		return dt, nil
+	}
+}
+
+func findStartSeekable(f *os.File, options Options, format retime.Format) (*Iterator, error) {
+
+	if options.prefilter {
+		outside, err := outsideRange(f, options, format)
+		if err != nil {
+			return nil, err
+		}
+		if outside {
+			return nil, io.EOF
+		}
+	}
 
 	// find block size
 	blockSize := int64(4096)
 
 	fileInfo, err := f.Stat()
 	if err != nil {
-		return &bufio.Scanner{}, err
+		return nil, err
 	}
 	size := fileInfo.Size()
+
+	// A file of at most one block has no block boundary to search.
+	if size <= blockSize {
+		if _, err := f.Seek(0, os.SEEK_SET); err != nil {
+			return nil, err
+		}
+		return newIterator(f.Name(), f, 0), nil
+	}
+
 	min := int64(0)
 	max := size / blockSize
 	var mid int64
 
-	var ignoreErrors = options.skipDateless || options.multiline
-
 	for max-min > 1 {
 		mid = (max + min) / 2
-		f.Seek(mid*blockSize, os.SEEK_SET)
-		scanner := bufio.NewScanner(f)
-
-		_, err := readline(scanner) // skip partial line
+		dt, err := probeDate(f, mid*blockSize, options, format)
 		if err != nil {
-			return scanner, err
-		}
-
-		var dt time.Time
-
-		for {
-			line, err := readline(scanner)
-			if err != nil {
-				return scanner, err
-			}
-
-			dt, err = format.Extract(line)
-			dt = fixtime.AddYear(dt, now)
-			if err != nil && ignoreErrors {
-				continue
-			}
-			if err != nil {
-				log.Fatalln("Aborting. Found line without date:", line)
-			}
-			break
-			// optimization: while searching next line we entered next block
+			return nil, err
 		}
 
 		if dt.Before(options.from) {
@@ -370,15 +3298,167 @@ func findStartSeekable(f *os.File, options Options, format retime.Format) (*bufi
 	min = min * blockSize
 	_, err = f.Seek(min, os.SEEK_SET)
 	if err != nil {
-		return &bufio.Scanner{}, err
+		return nil, err
 	}
-	scanner := bufio.NewScanner(f)
+	i := newIterator(f.Name(), f, min)
 	if min > 0 {
-		_, err := readline(scanner) // skip partial line
+		_, err := i.readline() // skip partial line
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return i, nil
+}
+
+// sortEntry is a dated line with the lines without date behind it.
+type sortEntry struct {
+	Time  time.Time
+	Lines []string
+}
+
+// sortLines writes the lines of f ordered by date to a temporary file and
+// returns it positioned at its start. Lines without date stay behind
+// their preceding dated line. The caller has to remove the file. Beyond
+// --limit-memory, sorted runs of lines are spilled to disk and merged.
+func sortLines(f *os.File, options Options, format retime.Format) (*os.File, error) {
+	var entries []sortEntry
+	var size int
+	var spilled *spillFile
+
+	sortEntries := func() {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Time.Before(entries[j].Time)
+		})
+	}
+	spill := func() error {
+		var err error
+		if spilled == nil {
+			if spilled, err = newSpillFile(); err != nil {
+				return err
+			}
+		}
+		sortEntries()
+		for _, e := range entries {
+			if err := spilled.add(e); err != nil {
+				return err
+			}
+		}
+		entries, size = nil, 0
+		return spilled.endRun()
+	}
+
+	scanner := newScanner(f)
+	for {
+		line, err := readline(scanner)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		dt, err := extract(line, options, format)
+		if err != nil && !options.passes(line, err) {
+			abortOnDateError(f.Name(), 0, err, line)
+		}
+		size += len(line)
+		if err != nil && len(entries) > 0 {
+			last := &entries[len(entries)-1]
+			last.Lines = append(last.Lines, line)
+			continue
+		}
 		if err != nil {
-			return scanner, err
+			dt = time.Time{}
+		}
+		if options.limitMemory > 0 && size > options.limitMemory {
+			if err := spill(); err != nil {
+				return nil, err
+			}
+			size = len(line)
+		}
+		entries = append(entries, sortEntry{Time: dt, Lines: []string{line}})
+	}
+	sortEntries()
+	if spilled != nil {
+		defer spilled.remove()
+	}
+
+	tmp, err := ioutil.TempFile("", "go-dategrep")
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(tmp)
+	write := func(e sortEntry) {
+		for _, line := range e.Lines {
+			w.WriteString(line)
+			w.WriteString("\n")
+		}
+	}
+	if spilled == nil {
+		for _, e := range entries {
+			write(e)
 		}
+	} else {
+		err = mergeRuns(spilled, entries, write)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		_, err = tmp.Seek(0, os.SEEK_SET)
 	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return tmp, nil
+}
 
-	return scanner, nil
+// mergeRuns calls write for the entries of the sorted runs in spilled and
+// the sorted entries after them ordered by date. Entries of equal date
+// keep their order in the input.
+func mergeRuns(spilled *spillFile, entries []sortEntry, write func(sortEntry)) error {
+	runs := spilled.runs()
+	decoders := make([]*gob.Decoder, runs)
+	heads := make([]*sortEntry, runs+1)
+	next := func(k int) error {
+		heads[k] = nil
+		if k == runs {
+			if len(entries) > 0 {
+				heads[k], entries = &entries[0], entries[1:]
+			}
+			return nil
+		}
+		var e sortEntry
+		err := decoders[k].Decode(&e)
+		if err == io.EOF {
+			return nil
+		}
+		heads[k] = &e
+		return err
+	}
+	for k := 0; k <= runs; k++ {
+		if k < runs {
+			decoders[k] = spilled.run(k)
+		}
+		if err := next(k); err != nil {
+			return err
+		}
+	}
+	for {
+		min := -1
+		for k, e := range heads {
+			if e != nil && (min < 0 || e.Time.Before(heads[min].Time)) {
+				min = k
+			}
+		}
+		if min < 0 {
+			return nil
+		}
+		write(*heads[min])
+		if err := next(min); err != nil {
+			return err
+		}
+	}
 }
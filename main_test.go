@@ -1,7 +1,16 @@
 package main
 
 import (
+	"container/heap"
+	"fmt"
 	"github.com/mdom/dtgrep/fixtime"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -28,6 +37,26 @@ func TestAddYear(t *testing.T) {
 		}
 	}
 
+	// A log straddling New Year read shortly after midnight
+	now, _ = time.Parse(time.RFC3339, "2017-01-01T00:30:00Z")
+
+	tests = []FillDateTest{
+		{"0000-12-31T23:59:59Z", "2016-12-31T23:59:59Z"},
+		{"0000-01-01T00:00:00Z", "2017-01-01T00:00:00Z"},
+		{"0000-01-01T00:29:59Z", "2017-01-01T00:29:59Z"},
+		// clocks running a few hours ahead stay in this year
+		{"0000-01-01T05:00:00Z", "2017-01-01T05:00:00Z"},
+		{"0000-01-01T07:00:00Z", "2016-01-01T07:00:00Z"},
+	}
+
+	for _, v := range tests {
+		argument, _ := time.Parse(time.RFC3339, v.argument)
+		result, _ := time.Parse(time.RFC3339, v.result)
+		if dt := fixtime.AddYear(argument, now); !dt.Equal(result) {
+			t.Errorf("AddYear(%v) returned %v, expected %v", argument, dt, result)
+		}
+	}
+
 }
 
 func TestDateRange(t *testing.T) {
@@ -54,4 +83,498 @@ func TestDateRange(t *testing.T) {
 		t.Error("specified to with duration")
 	}
 
+	s, e = dateRange(time.Time{}, to, time.Duration(0))
+	if !s.Equal(epoch) || e.String() != "2016-05-09 11:40:00 +0000 UTC" {
+		t.Error("unspecified from should default to epoch")
+	}
+
+	zero, _ := time.Parse(time.RFC3339, "0000-01-01T00:00:00Z")
+	if !s.Before(zero) {
+		t.Error("epoch must be before any parsed date")
+	}
+
+}
+
+func TestEscapeTSV(t *testing.T) {
+	tests := []struct {
+		field, escaped string
+	}{
+		{"foo bar", "foo bar"},
+		{"foo\tbar", `foo\tbar`},
+		{"foo\nbar\r", `foo\nbar\r`},
+		{`C:\foo\tbar`, `C:\\foo\\tbar`},
+	}
+	for _, v := range tests {
+		if got := escapeTSV(v.field); got != v.escaped {
+			t.Errorf("escapeTSV(%q) returned %q, expected %q", v.field, got, v.escaped)
+		}
+	}
+}
+
+func TestExpandGlobs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dtgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a.log", "b.log", "c.txt", "d[1].log"} {
+		ioutil.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+
+	j := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		args, files []string
+	}{
+		{[]string{"-", j("c.txt")}, []string{"-", j("c.txt")}},
+		{[]string{j("*.log")}, []string{j("a.log"), j("b.log"), j("d[1].log")}},
+		{[]string{j("?.txt"), j("[ab].log")}, []string{j("c.txt"), j("a.log"), j("b.log")}},
+		{[]string{j("d[1].log")}, []string{j("d[1].log")}},
+	}
+
+	for _, v := range tests {
+		files, err := expandGlobs(v.args)
+		if err != nil || !reflect.DeepEqual(files, v.files) {
+			t.Errorf("expandGlobs(%v) returned %v, %v, expected %v", v.args, files, err, v.files)
+		}
+	}
+
+	for _, arg := range []string{j("*.gz"), j("[.log")} {
+		if files, err := expandGlobs([]string{j("a.log"), arg}); err == nil {
+			t.Errorf("expandGlobs(%v) returned %v without error", arg, files)
+		}
+	}
+}
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		result   string
+	}{
+		{0, "0 milliseconds"},
+		{time.Millisecond, "1 millisecond"},
+		{250*time.Millisecond + 400*time.Microsecond, "250 milliseconds"},
+		{999*time.Millisecond + 600*time.Microsecond, "1 second"},
+		{time.Second, "1 second"},
+		{59*time.Second + 600*time.Millisecond, "1 minute"},
+		{90 * time.Second, "1 minute 30 seconds"},
+		{time.Hour + 23*time.Minute + 45*time.Second + 600*time.Millisecond, "1 hour 24 minutes"},
+		{time.Hour + 23*time.Minute + 29*time.Second, "1 hour 23 minutes"},
+		{2 * time.Hour, "2 hours"},
+		{24 * time.Hour, "1 day"},
+		{3*24*time.Hour + 4*time.Hour + 10*time.Minute, "3 days 4 hours"},
+		{400 * 24 * time.Hour, "400 days"},
+		{-5 * time.Second, "-5 seconds"},
+	}
+	for _, v := range tests {
+		if r := humanizeDuration(v.duration); r != v.result {
+			t.Errorf("humanizeDuration(%v) returned %q, expected %q", v.duration, r, v.result)
+		}
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int
+	}{
+		{"UTC", 0},
+		{"Z", 0},
+		{"+02:00", 2 * 3600},
+		{"+0530", 5*3600 + 30*60},
+		{"-03:30", -(3*3600 + 30*60)},
+		{"America/New_York", -5 * 3600},
+	}
+	for _, v := range tests {
+		loc, err := loadLocation(v.name)
+		if err != nil {
+			t.Errorf("loadLocation(%q) failed: %v", v.name, err)
+			continue
+		}
+		_, offset := time.Date(2016, 1, 1, 0, 0, 0, 0, loc).Zone()
+		if offset != v.offset {
+			t.Errorf("loadLocation(%q) has offset %d, expected %d", v.name, offset, v.offset)
+		}
+	}
+
+	for _, v := range []string{"+2:00", "+24:00", "+02:60", "02:00", "Foo/Bar"} {
+		if _, err := loadLocation(v); err == nil {
+			t.Errorf("loadLocation(%q) succeeded", v)
+		}
+	}
+}
+
+func TestSplitOutputNames(t *testing.T) {
+	files := []string{"a/syslog", "b/syslog", "-", "c/syslog", "messages.gz"}
+	result := []string{
+		filepath.Join("out", "syslog.matched"),
+		filepath.Join("out", "syslog.2.matched"),
+		filepath.Join("out", "stdin.matched"),
+		filepath.Join("out", "syslog.3.matched"),
+		filepath.Join("out", "messages.gz.matched"),
+	}
+	if names := splitOutputNames("out", files); !reflect.DeepEqual(names, result) {
+		t.Errorf("splitOutputNames returned %v, expected %v", names, result)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	defer func() { open = os.Open }()
+
+	var attempts int
+	failing := func(errno syscall.Errno, failures int) func(string) (*os.File, error) {
+		return func(name string) (*os.File, error) {
+			attempts++
+			if attempts <= failures {
+				return nil, &os.PathError{Op: "open", Path: name, Err: errno}
+			}
+			return os.Open(name)
+		}
+	}
+
+	options := Options{retryOpen: 2, retryDelay: time.Millisecond}
+
+	attempts, open = 0, failing(syscall.EAGAIN, 1)
+	file, err := openFile("main.go", options)
+	if err != nil || attempts != 2 {
+		t.Error("Transient error wasn't retried:", err, attempts)
+	}
+	file.Close()
+
+	attempts, open = 0, failing(syscall.ETIMEDOUT, 3)
+	_, err = openFile("main.go", options)
+	if err == nil || attempts != 3 {
+		t.Error("Retries weren't limited:", err, attempts)
+	}
+
+	attempts, open = 0, failing(syscall.ENOENT, 1)
+	_, err = openFile("main.go", options)
+	if err == nil || attempts != 1 {
+		t.Error("Permanent error was retried:", err, attempts)
+	}
+
+	attempts, open = 0, failing(syscall.EAGAIN, 1)
+	_, err = openFile("main.go", Options{})
+	if err == nil || attempts != 1 {
+		t.Error("Retried without --retry-open:", err, attempts)
+	}
+}
+
+func TestAddFormats(t *testing.T) {
+	formats := map[string]string{"rsyslog": "Jan _2 15:04:05"}
+	addFormats(formats, "app=2006-01-02 15:04:05;;bad; =01/02; short=15:04=05;rsyslog=Jan 2 15:04")
+
+	result := map[string]string{
+		"rsyslog": "Jan 2 15:04",
+		"app":     "2006-01-02 15:04:05",
+		"short":   "15:04=05",
+	}
+	if !reflect.DeepEqual(formats, result) {
+		t.Errorf("addFormats returned %v, expected %v", formats, result)
+	}
+}
+
+func TestNginxFormat(t *testing.T) {
+	format := newFormat("nginx", "", 69, false)
+	line := `203.0.113.7 - - [01/May/2010:12:00:01 +0200] "GET / HTTP/1.1" 200 612 "-" "curl/7.68.0"`
+	dt, err := format.Extract(line)
+	if err != nil {
+		t.Fatalf("Extracting from %q returned %v", line, err)
+	}
+	if result := time.Date(2010, 5, 1, 10, 0, 1, 0, time.UTC); !dt.Equal(result) {
+		t.Errorf("Extracting from %q returned %v, expected %v", line, dt, result)
+	}
+}
+
+func TestSyslog5424Format(t *testing.T) {
+	format := newFormat("syslog5424", "", 69, false)
+	if !format.HasYear() {
+		t.Fatal("syslog5424 has no year")
+	}
+	tests := []struct {
+		line   string
+		result time.Time
+	}{
+		{"2023-11-02T15:04:05.123456+00:00 host app[42]: started", time.Date(2023, 11, 2, 15, 4, 5, 123456000, time.UTC)},
+		{"2023-11-02T15:04:05+01:00 host app[42]: started", time.Date(2023, 11, 2, 14, 4, 5, 0, time.UTC)},
+		{"2023-11-02T15:04:05.1Z host app[42]: started", time.Date(2023, 11, 2, 15, 4, 5, 100000000, time.UTC)},
+	}
+	for _, v := range tests {
+		// extract must not replace the year of complete dates
+		dt, err := extract(v.line, Options{}, format)
+		if err != nil {
+			t.Errorf("Extracting from %q returned %v", v.line, err)
+		} else if !dt.Equal(v.result) {
+			t.Errorf("Extracting from %q returned %v, expected %v", v.line, dt, v.result)
+		}
+	}
+}
+
+func TestReadFileList(t *testing.T) {
+	f, err := ioutil.TempFile("", "dtgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("syslog\n\nsyslog.1.gz\r\nmy log\n")
+	f.Close()
+
+	names, err := readFileList(f.Name())
+	result := []string{"syslog", "syslog.1.gz", "my log"}
+	if err != nil || !reflect.DeepEqual(names, result) {
+		t.Errorf("readFileList returned %v, %v, expected %v", names, err, result)
+	}
+
+	if _, err := readFileList(f.Name() + ".missing"); err == nil {
+		t.Error("readFileList of a missing file returned no error")
+	}
+}
+
+func TestFollowReader(t *testing.T) {
+	f, err := ioutil.TempFile("", "dtgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.WriteString("line 1\n")
+	f.Seek(0, os.SEEK_SET)
+
+	defer func(saved time.Duration) { followInterval = saved }(followInterval)
+	followInterval = time.Millisecond
+	r := followReader{f}
+	buf := make([]byte, 64)
+	if n, err := r.Read(buf); err != nil || string(buf[:n]) != "line 1\n" {
+		t.Errorf("Read returned %q, %v", buf[:n], err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ioutil.WriteFile(f.Name(), []byte("new\n"), 0644)
+	}()
+	if n, err := r.Read(buf); err != nil || string(buf[:n]) != "new\n" {
+		t.Errorf("Read after truncation returned %q, %v", buf[:n], err)
+	}
+}
+
+func TestIsRegular(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	go func() {
+		w.WriteString("2010-05-01T00:00:00Z line 1\n2010-05-01T00:00:01Z line 2\n")
+		w.Close()
+	}()
+	if isRegular(r) {
+		t.Error("isRegular returned true for a pipe")
+	}
+
+	i := newIterator("pipe", r, 0)
+	format := newFormat("rfc3339", "", 69, false)
+	options := Options{from: time.Date(2010, 5, 1, 0, 0, 1, 0, time.UTC), to: future}
+	i.Scan(options, format)
+	if i.Err != nil || i.Line != "2010-05-01T00:00:01Z line 2" {
+		t.Errorf("Scanning the pipe returned %q, %v", i.Line, i.Err)
+	}
+
+	f, err := ioutil.TempFile("", "dtgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if !isRegular(f) {
+		t.Error("isRegular returned false for a file")
+	}
+}
+
+func TestExpandDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dtgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "logs", "old"), 0755)
+	for _, name := range []string{"logs/b.log", "logs/a.log", "logs/old/a.log.gz", "other.log"} {
+		ioutil.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	os.Symlink("a.log", filepath.Join(dir, "logs", "link.log"))
+
+	args := []string{"-", filepath.Join(dir, "logs"), filepath.Join(dir, "other.log"), filepath.Join(dir, "missing")}
+	files, err := expandDirs(args)
+	result := []string{
+		"-",
+		filepath.Join(dir, "logs", "a.log"),
+		filepath.Join(dir, "logs", "b.log"),
+		filepath.Join(dir, "logs", "old", "a.log.gz"),
+		filepath.Join(dir, "other.log"),
+		filepath.Join(dir, "missing"),
+	}
+	if err != nil || !reflect.DeepEqual(files, result) {
+		t.Errorf("expandDirs returned %v, %v, expected %v", files, err, result)
+	}
+}
+
+func TestIteratorsHeap(t *testing.T) {
+	base := time.Date(2010, 5, 1, 0, 0, 0, 0, time.UTC)
+	var iterators Iterators
+	for n, sec := range []int{5, 1, 3, 1, 4, 2, 0} {
+		iterators = append(iterators, &Iterator{Time: base.Add(time.Duration(sec) * time.Second), priority: n})
+	}
+	heap.Init(&iterators)
+
+	var order []int
+	for len(iterators) > 0 {
+		if next := iterators.next(); next != nil {
+			for _, i := range iterators[1:] {
+				if i.Time.Before(next.Time) {
+					t.Errorf("next returned %v, but %v is earlier", next.Time, i.Time)
+				}
+			}
+		}
+		order = append(order, heap.Pop(&iterators).(*Iterator).priority)
+	}
+	if result := []int{6, 1, 3, 5, 2, 4, 0}; !reflect.DeepEqual(order, result) {
+		t.Errorf("Popping iterators returned %v, expected %v", order, result)
+	}
+}
+
+func TestFindStartSeekable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dtgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each line is 32 bytes long, so 128 lines fill a block of 4096
+	// bytes.
+	base := time.Date(2010, 5, 1, 0, 0, 0, 0, time.UTC)
+	line := func(n int) string {
+		return base.Add(time.Duration(n)*time.Second).Format(time.RFC3339) + " line " + fmt.Sprintf("%05d", n)
+	}
+	lines := func(n int) string {
+		var s string
+		for k := 0; k < n; k++ {
+			s += line(k) + "\n"
+		}
+		return s
+	}
+
+	format := newFormat("rfc3339", "", 69, false)
+
+	tests := []struct {
+		name    string
+		lines   int
+		from    int
+		bisects bool
+	}{
+		{"empty", 0, 5, false},
+		{"small", 10, 5, false},
+		{"block", 128, 100, false},
+		{"block and a line", 129, 128, false},
+		{"four blocks", 512, 400, true},
+	}
+	for _, v := range tests {
+		filename := filepath.Join(dir, v.name)
+		ioutil.WriteFile(filename, []byte(lines(v.lines)), 0644)
+		f, err := os.Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		options := Options{from: base.Add(time.Duration(v.from) * time.Second), to: future}
+		i, err := findStartSeekable(f, options, format)
+		if err != nil {
+			t.Errorf("findStartSeekable for %s file returned %v", v.name, err)
+			f.Close()
+			continue
+		}
+		if v.bisects != (i.Offset > 0) {
+			t.Errorf("findStartSeekable for %s file started at %d", v.name, i.Offset)
+		}
+		i.Scan(options, format)
+		switch {
+		case v.lines == 0 && i.Err != io.EOF:
+			t.Errorf("Scan of %s file returned %q, %v", v.name, i.Line, i.Err)
+		case v.lines > 0 && i.Line != line(v.from):
+			t.Errorf("Scan of %s file returned %q, %v, expected %q", v.name, i.Line, i.Err, line(v.from))
+		}
+		f.Close()
+	}
+}
+
+func TestOutsideRange(t *testing.T) {
+	f, err := ioutil.TempFile("", "dtgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.WriteString("header\n2010-05-01T00:00:01Z line 1\n2010-05-01T00:00:02Z line 2\ntrailer\n")
+
+	format := newFormat("rfc3339", "", 69, false)
+	at := func(s int) time.Time { return time.Date(2010, 5, 1, 0, 0, s, 0, time.UTC) }
+
+	tests := []struct {
+		from, to int
+		outside  bool
+	}{
+		{0, 1, true},
+		{0, 2, false},
+		{2, 3, false},
+		{3, 4, true},
+	}
+	for _, v := range tests {
+		options := Options{from: at(v.from), to: at(v.to)}
+		outside, err := outsideRange(f, options, format)
+		if err != nil || outside != v.outside {
+			t.Errorf("outsideRange from %d to %d returned %v, %v", v.from, v.to, outside, err)
+		}
+	}
+}
+
+func TestDayOfFilename(t *testing.T) {
+	format := newFormat("2006-01-02", "", 69, false)
+
+	start, end, ok := dayOfFilename("/var/log/app-2023-11-02.log.gz", format, Options{})
+	if !ok || start.Format("2006-01-02 15:04") != "2023-11-02 00:00" || end.Format("2006-01-02 15:04") != "2023-11-03 00:00" {
+		t.Errorf("dayOfFilename returned %v, %v, %v", start, end, ok)
+	}
+
+	if _, _, ok := dayOfFilename("2023-11-02/app.log", format, Options{}); ok {
+		t.Error("dayOfFilename matched the name of the directory")
+	}
+}
+
+func TestNewFormatNames(t *testing.T) {
+	defer func(saved map[string]string) { formats = saved }(formats)
+	formats = map[string]string{
+		"blank": "",
+		"mjd":   "2006-01-02",
+	}
+
+	tests := []struct {
+		name, layout string
+	}{
+		// An empty layout is still a named format.
+		{"blank", ""},
+		// Named formats come before the built-in day numbers.
+		{"mjd", "2006-01-02"},
+		{"jd", ""},
+		{"15:04:05", "15:04:05"},
+	}
+	for _, v := range tests {
+		format := newFormat(v.name, "", 69, false)
+		if v.name == "jd" {
+			if !strings.HasPrefix(format.String(), "day number") {
+				t.Errorf("newFormat(%q) returned %v", v.name, format)
+			}
+			continue
+		}
+		if layout := strings.SplitN(format.String(), " ", 2)[0]; layout != v.layout {
+			t.Errorf("newFormat(%q) returned layout %q, expected %q", v.name, layout, v.layout)
+		}
+	}
 }
@@ -0,0 +1,195 @@
+// Package ncompress reads data compressed by compress(1), usually found
+// in files ending in .Z. Its LZW codes grow from 9 up to 16 bits and are
+// written in groups, so it can't be read by compress/lzw.
+package ncompress
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+const (
+	initBits      = 9
+	maxBitsLimit  = 16
+	bitsMask      = 0x1f
+	blockModeFlag = 0x80
+
+	// clearCode resets the table in block mode, the first free code
+	// comes after it.
+	clearCode = 256
+	firstCode = 257
+)
+
+var (
+	ErrHeader  = errors.New("ncompress: invalid header")
+	ErrCorrupt = errors.New("ncompress: corrupt input")
+)
+
+// Reader decompresses the data read from an underlying reader.
+type Reader struct {
+	r *bufio.Reader
+
+	maxBits   int
+	blockMode bool
+
+	// nBits is the current width of codes, maxCode the largest code
+	// of that width and maxMaxCode the size of the table.
+	nBits               int
+	maxCode, maxMaxCode int
+	freeEnt             int
+	clearFlag           bool
+
+	// buf holds a group of up to eight codes, offset and size are
+	// counted in bits.
+	buf          [maxBitsLimit + 2]byte
+	offset, size int
+
+	prefix []uint16
+	suffix []byte
+
+	started bool
+	oldCode int
+	finChar byte
+
+	stack, out []byte
+	err        error
+}
+
+// NewReader returns a Reader for r after checking the header of the
+// compressed data.
+func NewReader(r io.Reader) (*Reader, error) {
+	var header [3]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrHeader
+		}
+		return nil, err
+	}
+	if header[0] != 0x1f || header[1] != 0x9d {
+		return nil, ErrHeader
+	}
+	maxBits := int(header[2] & bitsMask)
+	if maxBits < initBits || maxBits > maxBitsLimit {
+		return nil, ErrHeader
+	}
+	z := &Reader{
+		r:          bufio.NewReader(r),
+		maxBits:    maxBits,
+		blockMode:  header[2]&blockModeFlag != 0,
+		nBits:      initBits,
+		maxCode:    1<<initBits - 1,
+		maxMaxCode: 1 << maxBits,
+		freeEnt:    clearCode,
+		prefix:     make([]uint16, 1<<maxBits),
+		suffix:     make([]byte, 1<<maxBits),
+	}
+	if z.blockMode {
+		z.freeEnt = firstCode
+	}
+	for code := 0; code < clearCode; code++ {
+		z.suffix[code] = byte(code)
+	}
+	return z, nil
+}
+
+func (z *Reader) Read(p []byte) (int, error) {
+	for len(z.out) == 0 {
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.err = z.decode()
+	}
+	n := copy(p, z.out)
+	z.out = z.out[n:]
+	return n, nil
+}
+
+// getCode returns the next code. compress writes the codes in groups of
+// eight and pads the group whenever the width of codes changes, so a
+// new group is read then.
+func (z *Reader) getCode() (int, error) {
+	if z.clearFlag || z.offset >= z.size || z.freeEnt > z.maxCode {
+		if z.freeEnt > z.maxCode {
+			z.nBits++
+			if z.nBits == z.maxBits {
+				z.maxCode = z.maxMaxCode
+			} else {
+				z.maxCode = 1<<z.nBits - 1
+			}
+		}
+		if z.clearFlag {
+			z.nBits = initBits
+			z.maxCode = 1<<initBits - 1
+			z.clearFlag = false
+		}
+		n, err := io.ReadFull(z.r, z.buf[:z.nBits])
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		z.offset = 0
+		z.size = n<<3 - (z.nBits - 1)
+		if z.size <= 0 {
+			return 0, io.EOF
+		}
+	}
+	pos := z.offset >> 3
+	bits := uint32(z.buf[pos]) | uint32(z.buf[pos+1])<<8 | uint32(z.buf[pos+2])<<16
+	code := int(bits>>uint(z.offset&7)) & (1<<uint(z.nBits) - 1)
+	z.offset += z.nBits
+	return code, nil
+}
+
+// decode puts the string of the next code into out.
+func (z *Reader) decode() error {
+	code, err := z.getCode()
+	if err != nil {
+		return err
+	}
+	if !z.started {
+		if code >= clearCode {
+			return ErrCorrupt
+		}
+		z.started = true
+		z.oldCode, z.finChar = code, byte(code)
+		z.out = append(z.stack[:0], z.finChar)
+		return nil
+	}
+	if code == clearCode && z.blockMode {
+		z.clearFlag = true
+		z.freeEnt = firstCode - 1
+		if code, err = z.getCode(); err != nil {
+			return err
+		}
+	}
+	inCode := code
+	stack := z.stack[:0]
+	if code >= z.freeEnt {
+		if code > z.freeEnt {
+			return ErrCorrupt
+		}
+		stack = append(stack, z.finChar)
+		code = z.oldCode
+	}
+	for code >= clearCode {
+		if len(stack) > len(z.suffix) {
+			return ErrCorrupt
+		}
+		stack = append(stack, z.suffix[code])
+		code = int(z.prefix[code])
+	}
+	z.finChar = byte(code)
+	stack = append(stack, z.finChar)
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+	z.stack, z.out = stack, stack
+
+	if z.freeEnt < z.maxMaxCode {
+		z.prefix[z.freeEnt] = uint16(z.oldCode)
+		z.suffix[z.freeEnt] = z.finChar
+		z.freeEnt++
+	}
+	z.oldCode = inCode
+	return nil
+}
@@ -0,0 +1,122 @@
+package ncompress
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+// compress encodes data like compress(1) in block mode, but clears the
+// table as soon as it's full instead of when the ratio drops.
+func compress(data []byte, maxBits int) []byte {
+	out := []byte{0x1f, 0x9d, byte(maxBits) | blockModeFlag}
+	if len(data) == 0 {
+		return out
+	}
+	nBits, maxCode, maxMaxCode := initBits, 1<<initBits-1, 1<<maxBits
+	freeEnt, clearFlag := firstCode, false
+
+	var buf []byte
+	var offset int
+	flush := func(n int) {
+		out = append(out, buf[:n]...)
+		buf, offset = nil, 0
+	}
+	output := func(code int) {
+		for len(buf) < nBits+2 {
+			buf = append(buf, 0)
+		}
+		for i := 0; i < nBits; i++ {
+			if code>>uint(i)&1 != 0 {
+				buf[(offset+i)>>3] |= 1 << uint((offset+i)&7)
+			}
+		}
+		offset += nBits
+		if offset == nBits<<3 {
+			flush(nBits)
+		}
+		if freeEnt > maxCode || clearFlag {
+			if offset > 0 {
+				flush(nBits)
+			}
+			if clearFlag {
+				nBits, maxCode, clearFlag = initBits, 1<<initBits-1, false
+			} else {
+				nBits++
+				if nBits == maxBits {
+					maxCode = maxMaxCode
+				} else {
+					maxCode = 1<<uint(nBits) - 1
+				}
+			}
+		}
+	}
+
+	table := make(map[[2]int]int)
+	ent := int(data[0])
+	for _, c := range data[1:] {
+		if code, ok := table[[2]int{ent, int(c)}]; ok {
+			ent = code
+			continue
+		}
+		output(ent)
+		if freeEnt < maxMaxCode {
+			table[[2]int{ent, int(c)}] = freeEnt
+			freeEnt++
+		} else {
+			table = make(map[[2]int]int)
+			freeEnt, clearFlag = firstCode, true
+			output(clearCode)
+		}
+		ent = int(c)
+	}
+	output(ent)
+	if offset > 0 {
+		flush((offset + 7) / 8)
+	}
+	return out
+}
+
+func testData() []byte {
+	var b bytes.Buffer
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&b, "2010-05-01T00:%02d:%02dZ line %d %x\n", i/60%60, i%60, i, i*i*7919)
+	}
+	return b.Bytes()
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		data    []byte
+		maxBits int
+	}{
+		{[]byte(""), 16},
+		{[]byte("a"), 16},
+		{[]byte("abababababababab"), 16},
+		{testData(), 16},
+		{testData(), 12},
+		{testData(), 9},
+	}
+	for _, test := range tests {
+		r, err := NewReader(bytes.NewReader(compress(test.data, test.maxBits)))
+		if err != nil {
+			t.Errorf("NewReader failed: %v", err)
+			continue
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("Reading %d bytes with %d bits failed: %v", len(test.data), test.maxBits, err)
+		} else if !bytes.Equal(data, test.data) {
+			t.Errorf("Reading %d bytes with %d bits returned %d different bytes", len(test.data), test.maxBits, len(data))
+		}
+	}
+}
+
+func TestReaderHeader(t *testing.T) {
+	for _, header := range []string{"", "\x1f", "\x1f\x8b\x08", "\x1f\x9d\x08", "\x1f\x9d\x91"} {
+		if _, err := NewReader(bytes.NewReader([]byte(header))); err != ErrHeader {
+			t.Errorf("NewReader(%q) returned %v, want ErrHeader", header, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	fileInfo, err := f.Stat()
+	return err == nil && fileInfo.Mode()&os.ModeCharDevice != 0
+}
+
+// findPager returns the command line of $PAGER or of less or more if
+// it's unset.
+func findPager() ([]string, error) {
+	if pager := strings.Fields(os.Getenv("PAGER")); len(pager) > 0 {
+		if _, err := exec.LookPath(pager[0]); err != nil {
+			return nil, errors.New(pager[0] + " not found")
+		}
+		return pager, nil
+	}
+	for _, name := range []string{"less", "more"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return []string{name}, nil
+		}
+	}
+	return nil, errors.New("neither less nor more found")
+}
+
+// startPager starts the pager and replaces os.Stdout by a pipe to it. The
+// returned function closes the pipe and waits for the pager to exit.
+func startPager() (func(), error) {
+	pager, err := findPager()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	// Like git, let less quit on short output and keep colors.
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = r
+	err = cmd.Start()
+	r.Close()
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	return func() {
+		w.Close()
+		cmd.Wait()
+		os.Stdout = stdout
+	}, nil
+}
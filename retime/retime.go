@@ -2,40 +2,479 @@ package retime
 
 import (
 	"bytes"
+	"errors"
+	"math"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// ErrNoMatch is returned by Extract if a string contains no timestamp.
+var ErrNoMatch = errors.New("no timestamp found")
+
+// ParseError is returned by Extract if a string contains something that
+// looks like a timestamp but can't be parsed, like "Feb 30 12:00:00".
+type ParseError struct {
+	Value string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	return "can't parse timestamp " + e.Value + ": " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// zoneOffsets maps zone abbreviations to their offset in seconds. The time
+// package only knows the abbreviations of the location used for parsing and
+// assumes UTC for all others.
+var zoneOffsets = map[string]int{
+	"UT":   0,
+	"UTC":  0,
+	"GMT":  0,
+	"EST":  -5 * 3600,
+	"EDT":  -4 * 3600,
+	"CST":  -6 * 3600,
+	"CDT":  -5 * 3600,
+	"MST":  -7 * 3600,
+	"MDT":  -6 * 3600,
+	"PST":  -8 * 3600,
+	"PDT":  -7 * 3600,
+	"WET":  0,
+	"CET":  1 * 3600,
+	"EET":  2 * 3600,
+	"WEST": 1 * 3600,
+	"CEST": 2 * 3600,
+	"EEST": 3 * 3600,
+}
+
+func fixZone(dt time.Time) time.Time {
+	name, offset := dt.Zone()
+	if offset != 0 {
+		return dt
+	}
+	if o, ok := zoneOffsets[name]; ok && o != 0 {
+		year, month, day := dt.Date()
+		hour, min, sec := dt.Clock()
+		return time.Date(year, month, day, hour, min, sec, dt.Nanosecond(), time.FixedZone(name, o))
+	}
+	return dt
+}
+
 type Format struct {
 	regexp *regexp.Regexp
 	layout string
 	loc    *time.Location
+
+	// literals is the text of strftime formats that layout has replaced
+	// by quote, in order. quoted matches a timestamp with each literal
+	// in a group named quote.
+	literals []string
+	quoted   *regexp.Regexp
+
+	// parseLayout is layout without fractional seconds after the
+	// seconds, which the time package then parses with any number of
+	// digits.
+	parseLayout string
+
+	// group is the index of the submatch containing the timestamp.
+	group int
+
+	hasYear bool
+
+	// twoDigitYear is set if the layout contains the year as "06". Those
+	// years below pivot are in the 21st century, the others in the 20th.
+	twoDigitYear bool
+	pivot        int
+
+	// unixDay is the day number of the Unix epoch for formats counting
+	// days instead of using a layout.
+	unixDay float64
+
+	// epochUnit is the unit of formats counting time since the Unix
+	// epoch as integer.
+	epochUnit time.Duration
 }
 
 func New(layout string, loc *time.Location) (Format, error) {
 	format := Format{
 		layout: layout,
 		loc:    loc,
+		pivot:  69,
 	}
-	regexp, err := compileToRegexp(layout)
+	if strings.Contains(layout, "%") {
+		var err error
+		layout, format.literals, err = fromStrftime(layout)
+		if err != nil {
+			return format, err
+		}
+		format.layout = layout
+	}
+	re, hasYear, err := compileToRegexp(layout)
 	if err != nil {
 		return format, err
 	}
-	format.regexp = regexp
+	if len(format.literals) > 0 {
+		plain, groups := re.String(), re.String()
+		for _, literal := range format.literals {
+			plain = strings.Replace(plain, quote, regexp.QuoteMeta(literal), 1)
+			groups = strings.Replace(groups, quote, `(?P<quote>`+regexp.QuoteMeta(literal)+`)`, 1)
+		}
+		re = regexp.MustCompile(plain)
+		format.quoted = regexp.MustCompile(`^(?:` + groups + `)$`)
+	}
+	format.regexp = re
+	format.hasYear = hasYear
+	format.twoDigitYear = hasTwoDigitYear(layout)
+	format.parseLayout = stripFractions(layout)
 	return format, nil
 }
 
+// NewMJD returns a format for Modified Julian Dates, fractional days
+// since 1858-11-17T00:00:00Z.
+func NewMJD() Format {
+	return newDayNumber(40587)
+}
+
+// NewJD returns a format for Julian Dates, fractional days since
+// noon of January 1, 4713 BC.
+func NewJD() Format {
+	return newDayNumber(2440587.5)
+}
+
+// newDayNumber returns a format for day numbers at the start of a line,
+// so numbers in the text of lines aren't taken as dates.
+func newDayNumber(unixDay float64) Format {
+	return Format{
+		regexp:  regexp.MustCompile(`^\d+(?:\.\d+)?\b`),
+		loc:     time.UTC,
+		hasYear: true,
+		unixDay: unixDay,
+	}
+}
+
+// NewEpoch returns a format for integer times since the Unix epoch at
+// the start of a line, counted in unit. Unit must divide a second.
+func NewEpoch(unit time.Duration) Format {
+	return Format{
+		regexp:    regexp.MustCompile(`^\d+\b`),
+		loc:       time.UTC,
+		hasYear:   true,
+		epochUnit: unit,
+	}
+}
+
+// String describes the layout and regular expression of f.
+func (f Format) String() string {
+	if f.unixDay != 0 {
+		return "day number since " + strconv.FormatFloat(f.unixDay, 'f', -1, 64)
+	}
+	if f.epochUnit != 0 {
+		return "epoch in " + f.epochUnit.String()
+	}
+	return expand(f.layout, f.literals) + " " + f.regexp.String()
+}
+
+// HasYear reports whether the layout contains a year. Dates extracted
+// with formats without year are in year zero.
+func (f Format) HasYear() bool {
+	return f.hasYear
+}
+
+// WithYearPivot returns a copy of f that puts two-digit years below
+// pivot in the 21st century and the others in the 20th. The time
+// package uses 69 as pivot.
+func (f Format) WithYearPivot(pivot int) Format {
+	f.pivot = pivot
+	return f
+}
+
+// Anchor returns a copy of f that only finds timestamps at the start of a
+// string.
+func (f Format) Anchor() Format {
+	f.regexp = regexp.MustCompile(`^(?:` + f.regexp.String() + `)`)
+	return f
+}
+
+// WithPattern returns a copy of f that searches timestamps with pattern
+// instead of a regular expression derived from the layout. The timestamp
+// is taken from the group named ts, the first group or the whole match.
+func (f Format) WithPattern(pattern string) (Format, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return f, err
+	}
+	f.regexp = re
+	f.group = 0
+	if i := re.SubexpIndex("ts"); i > 0 {
+		f.group = i
+	} else if re.NumSubexp() > 0 {
+		f.group = 1
+	}
+	return f, nil
+}
+
+// Render formats dt like the timestamps found by f. Fractional seconds
+// are kept even if the layout has none. It returns false for formats
+// without layout like epoch.
+func (f Format) Render(dt time.Time) (string, bool) {
+	if f.layout == "" {
+		return "", false
+	}
+	layout := f.layout
+	if i := strings.Index(layout, "05"); i >= 0 && layout == f.parseLayout {
+		layout = layout[:i+2] + ".999999999" + layout[i+2:]
+	}
+	return expand(dt.Format(layout), f.literals), true
+}
+
+// Index returns the start and end of the first timestamp in s, like
+// regexp.FindStringIndex, or nil if there is none.
+func (f *Format) Index(s string) []int {
+	match := f.regexp.FindStringSubmatchIndex(s)
+	if match == nil || match[2*f.group] < 0 {
+		return nil
+	}
+	return match[2*f.group : 2*f.group+2]
+}
+
+// Extract returns the first timestamp found in s. The error is either
+// ErrNoMatch or a *ParseError.
 func (f *Format) Extract(s string) (time.Time, error) {
-	match := f.regexp.FindString(s)
-	return time.ParseInLocation(f.layout, match, f.loc)
+	loc := f.Index(s)
+	if loc == nil {
+		return time.Time{}, ErrNoMatch
+	}
+	value := s[loc[0]:loc[1]]
+	if f.unixDay != 0 {
+		return f.parseDayNumber(value)
+	}
+	if f.epochUnit != 0 {
+		return f.parseEpoch(value)
+	}
+	parsed := value
+	if f.quoted != nil {
+		parsed = f.unquote(value)
+	}
+	dt, err := time.ParseInLocation(f.parseLayout, parsed, f.loc)
+	if err != nil {
+		return dt, &ParseError{Value: value, Err: err}
+	}
+	if f.twoDigitYear {
+		dt = f.fixCentury(dt)
+	}
+	return fixZone(dt), nil
+}
+
+// unquote replaces the literals of a strftime format in value by quote,
+// so value matches the layout.
+func (f *Format) unquote(value string) string {
+	match := f.quoted.FindStringSubmatchIndex(value)
+	if match == nil {
+		return value
+	}
+	var buffer bytes.Buffer
+	end := 0
+	for k, name := range f.quoted.SubexpNames() {
+		if name == "quote" {
+			buffer.WriteString(value[end:match[2*k]])
+			buffer.WriteString(quote)
+			end = match[2*k+1]
+		}
+	}
+	buffer.WriteString(value[end:])
+	return buffer.String()
+}
+
+// expand replaces each quote in s by the next of literals.
+func expand(s string, literals []string) string {
+	for _, literal := range literals {
+		s = strings.Replace(s, quote, literal, 1)
+	}
+	return s
+}
+
+func (f *Format) fixCentury(dt time.Time) time.Time {
+	year := dt.Year()%100 + 1900
+	if dt.Year()%100 < f.pivot {
+		year += 100
+	}
+	if year == dt.Year() {
+		return dt
+	}
+	hour, min, sec := dt.Clock()
+	return time.Date(year, dt.Month(), dt.Day(), hour, min, sec, dt.Nanosecond(), dt.Location())
+}
+
+// hasTwoDigitYear reports whether layout contains "06" outside of
+// "2006".
+func hasTwoDigitYear(layout string) bool {
+	return strings.Contains(strings.Replace(layout, "2006", "", -1), "06")
+}
+
+// strftimeDirectives maps strftime directives to their layout in the
+// time package.
+var strftimeDirectives = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'e': "_2",
+	'H': "15",
+	'M': "04",
+	'S': "05",
+	'b': "Jan",
+	'a': "Mon",
+	'z': "-0700",
+	'Z': "MST",
+	'T': "15:04:05",
+	'F': "2006-01-02",
+}
+
+// quote stands for literal text of strftime formats in layouts. The time
+// package can't escape text in layouts, so "Mon" or "100" would be taken
+// as fields.
+const quote = "\x00"
+
+// fromStrftime translates a strftime format like "%b %e %H:%M:%S" to a
+// layout. %f are the microseconds after a dot or comma. Text between
+// directives that contains letters, digits or underscores is replaced by
+// quote and returned in literals.
+func fromStrftime(format string) (string, []string, error) {
+	var buffer bytes.Buffer
+	var literals []string
+	var text []byte
+	flush := func() {
+		if bytes.IndexFunc(text, isWordRune) >= 0 {
+			literals = append(literals, string(text))
+			buffer.WriteString(quote)
+		} else {
+			buffer.Write(text)
+		}
+		text = text[:0]
+	}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			text = append(text, format[i])
+			continue
+		}
+		if i+1 == len(format) {
+			return "", nil, errors.New("strftime directive missing after % at end of format")
+		}
+		i++
+		switch format[i] {
+		case '%':
+			text = append(text, '%')
+			continue
+		case 'f':
+			n := len(text)
+			if n == 0 || text[n-1] != '.' && text[n-1] != ',' {
+				return "", nil, errors.New("strftime directive %f must follow a dot or comma")
+			}
+			sep := text[n-1]
+			text = text[:n-1]
+			flush()
+			buffer.WriteByte(sep)
+			buffer.WriteString("000000")
+			continue
+		}
+		layout, ok := strftimeDirectives[format[i]]
+		if !ok {
+			return "", nil, errors.New("unknown strftime directive %" + string(format[i]))
+		}
+		flush()
+		buffer.WriteString(layout)
+	}
+	flush()
+	return buffer.String(), literals, nil
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+}
+
+// parseDayNumber converts a fractional day number to a time, rounded to
+// the microsecond.
+func (f *Format) parseDayNumber(value string) (time.Time, error) {
+	days, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, &ParseError{Value: value, Err: err}
+	}
+	sec, frac := math.Modf((days - f.unixDay) * 86400)
+	usec := math.Round(frac * 1e6)
+	return time.Unix(int64(sec), int64(usec)*1000).In(f.loc), nil
+}
+
+// parseEpoch converts an integer count of f.epochUnit since the Unix
+// epoch to a time.
+func (f *Format) parseEpoch(value string) (time.Time, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, &ParseError{Value: value, Err: err}
+	}
+	perSecond := int64(time.Second / f.epochUnit)
+	return time.Unix(n/perSecond, n%perSecond*int64(f.epochUnit)).In(f.loc), nil
 }
 
 func prefixAt(s string, index int, prefix string) bool {
 	return len(s) >= index+len(prefix) && s[index:index+len(prefix)] == prefix
 }
 
-func compileToRegexp(layout string) (*regexp.Regexp, error) {
+// isFraction reports whether layout has fractional seconds like ".000"
+// or ",999" at index. Like in the time package, a dot followed by a zero
+// and another digit, as in "02.01.2006", separates other fields.
+func isFraction(layout string, index int) bool {
+	if index+1 >= len(layout) || layout[index] != '.' && layout[index] != ',' {
+		return false
+	}
+	ch := layout[index+1]
+	if ch != '0' && ch != '9' {
+		return false
+	}
+	j := index + 1
+	for j < len(layout) && layout[j] == ch {
+		j++
+	}
+	return j == len(layout) || layout[j] < '0' || layout[j] > '9'
+}
+
+// afterSeconds reports whether index in layout directly follows the
+// seconds "05" or "5".
+func afterSeconds(layout string, index int) bool {
+	if index < 1 || layout[index-1] != '5' {
+		return false
+	}
+	return index < 2 || layout[index-2] == '0' || layout[index-2] < '0' || layout[index-2] > '9'
+}
+
+// stripFractions removes fractional seconds following the seconds from
+// layout.
+func stripFractions(layout string) string {
 	var buffer bytes.Buffer
+	for i := 0; i < len(layout); {
+		if isFraction(layout, i) && afterSeconds(layout, i) {
+			j := i + 1
+			for j < len(layout) && layout[j] == layout[i+1] {
+				j++
+			}
+			i = j
+			continue
+		}
+		buffer.WriteByte(layout[i])
+		i++
+	}
+	return buffer.String()
+}
+
+// compileToRegexp translates layout to a regular expression. Seconds may
+// be followed by fractional seconds of any precision, like the time
+// package accepts them when parsing.
+func compileToRegexp(layout string) (*regexp.Regexp, bool, error) {
+	var buffer bytes.Buffer
+	var hasYear bool
 
 	l := len(layout)
 	for i := 0; i < l; {
@@ -58,7 +497,11 @@ func compileToRegexp(layout string) (*regexp.Regexp, error) {
 		// 01, 02, 03, 04, 05, 06
 		case l >= i+1 && layout[i] == '0' && '1' <= layout[i+1] && layout[i+1] <= '6':
 			buffer.WriteString(`\d\d`)
+			hasYear = hasYear || layout[i+1] == '6'
 			i += 2
+			if layout[i-1] == '5' && !isFraction(layout, i) {
+				buffer.WriteString(`(?:[.,]\d+)?`)
+			}
 		case prefixAt(layout, i, "15"):
 			buffer.WriteString(`\d\d`)
 			i += 2
@@ -67,6 +510,7 @@ func compileToRegexp(layout string) (*regexp.Regexp, error) {
 			i++
 		case prefixAt(layout, i, "2006"):
 			buffer.WriteString(`\d{4}`)
+			hasYear = true
 			i += 4
 		case layout[i] == '2': // day
 			buffer.WriteString(`\d`)
@@ -75,7 +519,7 @@ func compileToRegexp(layout string) (*regexp.Regexp, error) {
 			buffer.WriteString(`_\d{4}`)
 			i += 5
 		case prefixAt(layout, i, "_2"): //day
-			buffer.WriteString(`[ \d]\d`)
+			buffer.WriteString(`[ \d]?\d`)
 			i += 2
 		case layout[i] == '3': // hour12
 			buffer.WriteString(`\d?d`)
@@ -86,6 +530,9 @@ func compileToRegexp(layout string) (*regexp.Regexp, error) {
 		case layout[i] == '5': // second
 			buffer.WriteString(`\d\d`)
 			i++
+			if !isFraction(layout, i) {
+				buffer.WriteString(`(?:[.,]\d+)?`)
+			}
 		case prefixAt(layout, i, "PM"):
 			buffer.WriteString(`(PM|AM)`)
 			i += 2
@@ -122,18 +569,26 @@ func compileToRegexp(layout string) (*regexp.Regexp, error) {
 		case prefixAt(layout, i, "Z07"):
 			buffer.WriteString(`(Z|[+-]\d{2})`)
 			i += 3
-		case l >= i+1 && (layout[i:i+1] == ".0" || layout[i:i+1] == ".9"):
+		case isFraction(layout, i):
 			j := i + 1
-			ch := layout[i+2]
-			for j < l && layout[j] == ch {
+			for j < l && layout[j] == layout[i+1] {
 				j++
 			}
-			buffer.WriteString(`\.\d+`)
-			i = i + j + 1
+			sep := regexp.QuoteMeta(string(layout[i]))
+			switch {
+			case layout[i+1] == '9':
+				buffer.WriteString(`(?:` + sep + `\d+)?`)
+			case afterSeconds(layout, i):
+				buffer.WriteString(sep + `\d+`)
+			default:
+				buffer.WriteString(sep + `\d{` + strconv.Itoa(j-i-1) + `}`)
+			}
+			i = j
 		default:
 			buffer.WriteString(regexp.QuoteMeta(string(layout[i])))
 			i++
 		}
 	}
-	return regexp.Compile(buffer.String())
+	re, err := regexp.Compile(buffer.String())
+	return re, hasYear, err
 }
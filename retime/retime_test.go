@@ -1,6 +1,8 @@
 package retime
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -14,4 +16,392 @@ func TestCompileToRegexp(t *testing.T) {
 	if !dt2.Equal(dt1) {
 		t.Error("foo")
 	}
+
+	dt1, _ = time.ParseInLocation("Jan _2 15:04:05", "Feb  2 09:34:59", time.UTC)
+	dt2, _ = f.Extract("foo Feb 2 09:34:59 bar")
+
+	if !dt2.Equal(dt1) {
+		t.Error("Space padded day without padding not found")
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	f, _ := New("Jan _2 15:04:05", time.UTC)
+
+	for _, v := range []string{"", "foo", "Feb 12 09:34", "feb 12 09:34:59"} {
+		_, err := f.Extract(v)
+		if err != ErrNoMatch {
+			t.Errorf("Extract(%q) returned %v, expected ErrNoMatch", v, err)
+		}
+	}
+
+	for _, v := range []string{"Foo 12 09:34:59", "Feb 30 09:34:59", "Feb 12 25:34:59"} {
+		_, err := f.Extract(v)
+		if _, ok := err.(*ParseError); !ok {
+			t.Errorf("Extract(%q) returned %v, expected ParseError", v, err)
+		}
+	}
+}
+
+func TestExtractZoneAbbreviation(t *testing.T) {
+	tests := []struct {
+		layout string
+		line   string
+		result string
+	}{
+		{time.RFC1123, "Mon, 02 Jan 2006 15:04:05 EST foo", "2006-01-02T20:04:05Z"},
+		{time.RFC1123, "Tue, 02 Jan 2006 15:04:05 PDT foo", "2006-01-02T22:04:05Z"},
+		{time.RFC1123, "Mon, 02 Jan 2006 15:04:05 GMT foo", "2006-01-02T15:04:05Z"},
+		{time.RFC822, "foo 02 Jan 06 15:04 CET bar", "2006-01-02T14:04:00Z"},
+		{time.RFC822, "foo 02 Jan 06 15:04 UTC bar", "2006-01-02T15:04:00Z"},
+	}
+
+	for _, v := range tests {
+		f, err := New(v.layout, time.UTC)
+		if err != nil {
+			t.Fatal("Can't create format", v.layout, ":", err)
+		}
+		dt, err := f.Extract(v.line)
+		result, _ := time.Parse(time.RFC3339, v.result)
+		if err != nil || !dt.Equal(result) {
+			t.Errorf("Extract(%q) returned %v, expected %v", v.line, dt, result)
+		}
+	}
+}
+
+func TestAnchor(t *testing.T) {
+	f, _ := New("Jan _2 15:04:05", time.UTC)
+	f = f.Anchor()
+
+	if _, err := f.Extract("Feb 12 09:34:59 bar"); err != nil {
+		t.Error("Anchored format didn't find timestamp at start:", err)
+	}
+
+	if _, err := f.Extract("foo Feb 12 09:34:59 bar"); err != ErrNoMatch {
+		t.Error("Anchored format found timestamp after start")
+	}
+}
+
+func TestWithPattern(t *testing.T) {
+	f, _ := New("2006-01-02 15:04:05", time.UTC)
+	result, _ := time.Parse(time.RFC3339, "2016-05-09T10:40:00Z")
+
+	tests := []struct {
+		pattern string
+		line    string
+	}{
+		{`^\S+ (?P<ts>\d{4}-\d\d-\d\d \d\d:\d\d:\d\d) `, "host-1 2016-05-09 10:40:00 foo"},
+		{`^\S+ (?P<ts>\d{4}-\d\d-\d\d \d\d:\d\d:\d\d) `, "web-42.example.com 2016-05-09 10:40:00 foo"},
+		{`^(\S+) \S+ (?P<ts>.{19})`, "a 2015-01-01 2016-05-09 10:40:00 foo"},
+		{`at (.{19})$`, "started 2015-01-01 00:00:00 at 2016-05-09 10:40:00"},
+		{`\d{4}-\d\d-\d\d \d\d:\d\d:\d\d`, "[main] 2016-05-09 10:40:00 foo"},
+	}
+
+	for _, v := range tests {
+		p, err := f.WithPattern(v.pattern)
+		if err != nil {
+			t.Fatal("Can't use pattern", v.pattern, ":", err)
+		}
+		dt, err := p.Extract(v.line)
+		if err != nil || !dt.Equal(result) {
+			t.Errorf("Extract(%q) with pattern %q returned %v, %v", v.line, v.pattern, dt, err)
+		}
+	}
+
+	p, _ := f.WithPattern(`^(?:foo (?P<ts>.{19})|bar)`)
+	if _, err := p.Extract("bar 2016-05-09 10:40:00"); err != ErrNoMatch {
+		t.Error("Extract with unmatched group returned", err)
+	}
+
+	if _, err := f.WithPattern(`(`); err == nil {
+		t.Error("Invalid pattern accepted")
+	}
+}
+
+func TestHasYear(t *testing.T) {
+	tests := []struct {
+		layout  string
+		hasYear bool
+	}{
+		{"Jan _2 15:04:05", false},
+		{"02/Jan/2006:15:04:05 -0700", true},
+		{time.RFC822, true},
+		{time.ANSIC, true},
+		{time.UnixDate, true},
+		{"15:04:05", false},
+	}
+	for _, v := range tests {
+		f, _ := New(v.layout, time.UTC)
+		if f.HasYear() != v.hasYear {
+			t.Errorf("HasYear for %q returned %v", v.layout, f.HasYear())
+		}
+	}
+}
+
+func TestExtractWeekday(t *testing.T) {
+	tests := []struct {
+		layout string
+		line   string
+		result string
+	}{
+		{time.ANSIC, "Tue Jan  2 15:04:05 2006 foo", "2006-01-02T15:04:05Z"},
+		{time.ANSIC, "foo Mon Jan 12 15:04:05 2006", "2006-01-12T15:04:05Z"},
+		{time.UnixDate, "Mon Jan  2 15:04:05 UTC 2006 foo", "2006-01-02T15:04:05Z"},
+		{time.UnixDate, "Mon Jan 2 10:04:05 EST 2006 foo", "2006-01-02T15:04:05Z"},
+	}
+
+	for _, v := range tests {
+		f, _ := New(v.layout, time.UTC)
+		dt, err := f.Extract(v.line)
+		result, _ := time.Parse(time.RFC3339, v.result)
+		if err != nil || !dt.Equal(result) {
+			t.Errorf("Extract(%q) returned %v, %v, expected %v", v.line, dt, err, result)
+		}
+	}
+}
+
+func TestExtractDayNumber(t *testing.T) {
+	tests := []struct {
+		format Format
+		line   string
+		result string
+	}{
+		{NewMJD(), "55317.5 shutter open", "2010-05-01T12:00:00Z"},
+		{NewMJD(), "40587 foo", "1970-01-01T00:00:00Z"},
+		{NewMJD(), "0.0 foo", "1858-11-17T00:00:00Z"},
+		{NewMJD(), "51544.00001157 foo", "2000-01-01T00:00:01Z"},
+		{NewJD(), "2455318.0 shutter open", "2010-05-01T12:00:00Z"},
+		{NewJD(), "2451544.5 foo", "2000-01-01T00:00:00Z"},
+	}
+
+	for _, v := range tests {
+		dt, err := v.format.Extract(v.line)
+		result, _ := time.Parse(time.RFC3339, v.result)
+		if err != nil || !dt.Round(time.Second).Equal(result) {
+			t.Errorf("Extract(%q) returned %v, %v, expected %v", v.line, dt, err, result)
+		}
+	}
+
+	f := NewMJD()
+	for _, line := range []string{"foo", "  at Foo.java:42", "shutter 55317.5"} {
+		if _, err := f.Extract(line); err != ErrNoMatch {
+			t.Errorf("Extract(%q) without leading day number returned %v", line, err)
+		}
+	}
+}
+
+func TestExtractEpoch(t *testing.T) {
+	tests := []struct {
+		format Format
+		line   string
+		result string
+	}{
+		{NewEpoch(time.Second), "1272715200 shutter open", "2010-05-01T12:00:00Z"},
+		{NewEpoch(time.Second), "0 foo", "1970-01-01T00:00:00Z"},
+		{NewEpoch(time.Millisecond), "1272715200123 foo", "2010-05-01T12:00:00.123Z"},
+		{NewEpoch(time.Nanosecond), "1272715200000000042 foo", "2010-05-01T12:00:00.000000042Z"},
+	}
+
+	for _, v := range tests {
+		dt, err := v.format.Extract(v.line)
+		result, _ := time.Parse(time.RFC3339Nano, v.result)
+		if err != nil || !dt.Equal(result) {
+			t.Errorf("Extract(%q) returned %v, %v, expected %v", v.line, dt, err, result)
+		}
+	}
+
+	f := NewEpoch(time.Second)
+	if !f.HasYear() {
+		t.Error("Epoch format has no year")
+	}
+	for _, line := range []string{"foo", "  at Foo.java:42", "shutter 1272715200"} {
+		if _, err := f.Extract(line); err != ErrNoMatch {
+			t.Errorf("Extract(%q) without leading epoch returned %v", line, err)
+		}
+	}
+	if _, err := f.Extract("99999999999999999999 foo"); err == nil || err == ErrNoMatch {
+		t.Error("Extract with overflowing epoch returned", err)
+	}
+}
+
+func TestExtractDots(t *testing.T) {
+	tests := []struct {
+		layout string
+		line   string
+		result string
+	}{
+		{"02.01.2006 15:04:05", "foo 02.01.2024 15:04:05 bar", "2024-01-02T15:04:05Z"},
+		{"02.01.2006 15:04:05", "02.01.2024 15:04:05.123 bar", "2024-01-02T15:04:05.123Z"},
+		{"02.01.2006 15:04:05.000", "02.01.2024 15:04:05.123 bar", "2024-01-02T15:04:05.123Z"},
+		{"02.01.2006 15:04:05,000", "02.01.2024 15:04:05,123 bar", "2024-01-02T15:04:05.123Z"},
+		{"2006-01-02 15:04:05.999", "2024-01-02 15:04:05.5 bar", "2024-01-02T15:04:05.5Z"},
+		{"2006-01-02 15:04:05.999", "2024-01-02 15:04:05 bar", "2024-01-02T15:04:05Z"},
+	}
+
+	for _, v := range tests {
+		f, _ := New(v.layout, time.UTC)
+		dt, err := f.Extract(v.line)
+		result, _ := time.Parse(time.RFC3339Nano, v.result)
+		if err != nil || !dt.Equal(result) {
+			t.Errorf("Extract(%q) with %q returned %v, %v, expected %v", v.line, v.layout, dt, err, result)
+		}
+	}
+}
+
+func TestExtractFractions(t *testing.T) {
+	tests := []struct {
+		layout string
+		line   string
+		result string
+	}{
+		{"2006-01-02 15:04:05", "2024-01-02 15:04:05 bar", "2024-01-02T15:04:05Z"},
+		{"2006-01-02 15:04:05", "2024-01-02 15:04:05.5 bar", "2024-01-02T15:04:05.5Z"},
+		{"2006-01-02 15:04:05", "2024-01-02 15:04:05,123456 bar", "2024-01-02T15:04:05.123456Z"},
+		{"2006-01-02 15:04:05", "2024-01-02 15:04:05.123456789 bar", "2024-01-02T15:04:05.123456789Z"},
+		{"2006-01-02 15:04:05.000", "2024-01-02 15:04:05.1 bar", "2024-01-02T15:04:05.1Z"},
+		{"2006-01-02 15:04:05.000", "2024-01-02 15:04:05.123456 bar", "2024-01-02T15:04:05.123456Z"},
+		{"2006-01-02 15:04:05.000000", "2024-01-02 15:04:05.123456789 bar", "2024-01-02T15:04:05.123456789Z"},
+		{"2006-01-02 15:04:05.999", "2024-01-02 15:04:05.000001 bar", "2024-01-02T15:04:05.000001Z"},
+		{"2006-01-02T15:04:05Z07:00", "2024-01-02T15:04:05.123456+01:00 bar", "2024-01-02T14:04:05.123456Z"},
+		{"Jan _2 15:04:05", "Jan  2 15:04:05.250 bar", "0000-01-02T15:04:05.25Z"},
+	}
+
+	for _, v := range tests {
+		f, _ := New(v.layout, time.UTC)
+		dt, err := f.Extract(v.line)
+		result, _ := time.Parse(time.RFC3339Nano, v.result)
+		if err != nil || !dt.Equal(result) {
+			t.Errorf("Extract(%q) with %q returned %v, %v, expected %v", v.line, v.layout, dt, err, result)
+		}
+	}
+
+	f, _ := New("2006-01-02 15:04:05.000", time.UTC)
+	if _, err := f.Extract("2024-01-02 15:04:05 bar"); err != ErrNoMatch {
+		t.Errorf("Extract without required fraction returned %v, expected ErrNoMatch", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	pacific := time.FixedZone("PST", -8*3600)
+	tests := []struct {
+		layout string
+		line   string
+		result string
+	}{
+		{"2006-01-02T15:04:05Z07:00", "2024-01-02T15:04:05Z", "2024-01-02T07:04:05-08:00"},
+		{"2006-01-02T15:04:05Z07:00", "2024-01-02T15:04:05.25Z", "2024-01-02T07:04:05.25-08:00"},
+		{"2006-01-02 15:04:05.000", "2024-01-02 15:04:05.100", "2024-01-02 07:04:05.100"},
+		{"Jan _2 15:04:05 MST", "Jan  2 03:04:05 UTC", "Jan  1 19:04:05 PST"},
+	}
+	for _, v := range tests {
+		f, _ := New(v.layout, time.UTC)
+		dt, err := f.Extract(v.line)
+		if err != nil {
+			t.Errorf("Extract(%q) failed: %v", v.line, err)
+			continue
+		}
+		if result, ok := f.Render(dt.In(pacific)); !ok || result != v.result {
+			t.Errorf("Render of %q with %q returned %q, expected %q", v.line, v.layout, result, v.result)
+		}
+	}
+
+	if _, ok := NewEpoch(time.Second).Render(time.Now()); ok {
+		t.Error("Render of epoch format succeeded")
+	}
+}
+
+func TestYearPivot(t *testing.T) {
+	tests := []struct {
+		pivot  int
+		line   string
+		result string
+	}{
+		{69, "01/02/68 foo", "2068-01-02T00:00:00Z"},
+		{69, "01/02/69 foo", "1969-01-02T00:00:00Z"},
+		{69, "01/02/99 foo", "1999-01-02T00:00:00Z"},
+		{50, "01/02/68 foo", "1968-01-02T00:00:00Z"},
+		{50, "01/02/49 foo", "2049-01-02T00:00:00Z"},
+		{0, "01/02/00 foo", "1900-01-02T00:00:00Z"},
+		{100, "01/02/99 foo", "2099-01-02T00:00:00Z"},
+		{90, "02/29/00 foo", "2000-02-29T00:00:00Z"},
+	}
+
+	for _, v := range tests {
+		f, _ := New("01/02/06", time.UTC)
+		f = f.WithYearPivot(v.pivot)
+		dt, err := f.Extract(v.line)
+		result, _ := time.Parse(time.RFC3339, v.result)
+		if err != nil || !dt.Equal(result) {
+			t.Errorf("Extract(%q) with pivot %d returned %v, %v, expected %v", v.line, v.pivot, dt, err, result)
+		}
+	}
+
+	f, _ := New("2006-01-02", time.UTC)
+	f = f.WithYearPivot(100)
+	dt, err := f.Extract("1968-01-02")
+	if err != nil || dt.Year() != 1968 {
+		t.Error("Pivot changed four-digit year to", dt, err)
+	}
+}
+
+func TestStrftime(t *testing.T) {
+	tests := []struct {
+		format string
+		layout string
+	}{
+		{"%b %e %H:%M:%S", "Jan _2 15:04:05"},
+		{"%Y-%m-%dT%H:%M:%S%z", "2006-01-02\x0015:04:05-0700"},
+		{"%d.%m.%y %T %Z", "02.01.06 15:04:05 MST"},
+		{"%F %H:%M:%S.%f", "2006-01-02 15:04:05.000000"},
+		{"%a, 100%% %F", "Mon\x002006-01-02"},
+		{"%T.%f, %%", "15:04:05.000000, %"},
+	}
+	for _, v := range tests {
+		f, err := New(v.format, time.UTC)
+		if err != nil || f.layout != v.layout {
+			t.Errorf("New(%q) returned layout %q, %v, expected %q", v.format, f.layout, err, v.layout)
+		}
+	}
+
+	for _, format := range []string{"%Y-%m-%d %Q", "%H:%M:%S%f", "%H:%M %"} {
+		if _, err := New(format, time.UTC); err == nil {
+			t.Errorf("New(%q) returned no error", format)
+		}
+	}
+
+	f, _ := New("%Y-%m-%d %H:%M:%S,%f", time.UTC)
+	dt, err := f.Extract("2010-05-01 12:00:01,250000 foo")
+	if result := time.Date(2010, 5, 1, 12, 0, 1, 250000000, time.UTC); err != nil || !dt.Equal(result) {
+		t.Errorf("Extract returned %v, %v, expected %v", dt, err, result)
+	}
+
+	// Literal text isn't taken as fields of the layout.
+	f, _ = New("at %H:%M:%S, Jan %d 100%%", time.UTC)
+	dt, err = f.Extract("started at 12:00:01, Jan 05 100% done")
+	if result := time.Date(0, 1, 5, 12, 0, 1, 0, time.UTC); err != nil || !dt.Equal(result) {
+		t.Errorf("Extract returned %v, %v, expected %v", dt, err, result)
+	}
+	if _, err := f.Extract("started at 12:00:01, Feb 05 100% done"); err != ErrNoMatch {
+		t.Errorf("Extract with other literal text returned %v", err)
+	}
+	if result, _ := f.Render(dt.Add(time.Hour)); result != "at 13:00:01, Jan 05 100%" {
+		t.Errorf("Render returned %q", result)
+	}
+	if !strings.HasPrefix(f.String(), "at 15:04:05, Jan 02 100% ") {
+		t.Errorf("String returned %q", f.String())
+	}
+}
+
+func TestIndex(t *testing.T) {
+	f, _ := New(time.RFC3339, time.UTC)
+	if loc := f.Index("foo 2010-05-01T00:00:01Z bar"); !reflect.DeepEqual(loc, []int{4, 24}) {
+		t.Errorf("Index returned %v, expected [4 24]", loc)
+	}
+	if loc := f.Index("foo bar"); loc != nil {
+		t.Errorf("Index without timestamp returned %v", loc)
+	}
+	f, _ = f.WithPattern(`at (\d\d:\d\d:\d\d)`)
+	if loc := f.Index("started at 12:00:01"); !reflect.DeepEqual(loc, []int{11, 19}) {
+		t.Errorf("Index with pattern returned %v, expected [11 19]", loc)
+	}
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// spillFile keeps runs of values that don't fit into --limit-memory in
+// a temporary file. Each run is encoded on its own, so runs can be read
+// independently of each other.
+type spillFile struct {
+	file *os.File
+	ends []int64
+
+	w   *bufio.Writer
+	enc *gob.Encoder
+}
+
+func newSpillFile() (*spillFile, error) {
+	file, err := ioutil.TempFile("", "go-dategrep")
+	if err != nil {
+		return nil, err
+	}
+	tempFiles = append(tempFiles, file.Name())
+	return &spillFile{file: file}, nil
+}
+
+// add appends v to the current run.
+func (s *spillFile) add(v interface{}) error {
+	if s.enc == nil {
+		s.w = bufio.NewWriter(s.file)
+		s.enc = gob.NewEncoder(s.w)
+	}
+	return s.enc.Encode(v)
+}
+
+// endRun ends the current run, the next value added starts a new one.
+func (s *spillFile) endRun() error {
+	if s.enc == nil {
+		return nil
+	}
+	s.enc = nil
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	end, err := s.file.Seek(0, os.SEEK_CUR)
+	if err != nil {
+		return err
+	}
+	s.ends = append(s.ends, end)
+	return nil
+}
+
+// runs returns the number of ended runs.
+func (s *spillFile) runs() int {
+	return len(s.ends)
+}
+
+// run returns a decoder of the values of run k. It returns io.EOF after
+// the last value.
+func (s *spillFile) run(k int) *gob.Decoder {
+	var start int64
+	if k > 0 {
+		start = s.ends[k-1]
+	}
+	return gob.NewDecoder(bufio.NewReader(io.NewSectionReader(s.file, start, s.ends[k]-start)))
+}
+
+func (s *spillFile) remove() {
+	s.file.Close()
+	os.Remove(s.file.Name())
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"github.com/mdom/dtgrep/retime"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpillFile(t *testing.T) {
+	s, err := newSpillFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.remove()
+
+	runs := [][]string{{"a", "b"}, {"c"}, {"d", "e", "f"}}
+	for _, run := range runs {
+		for _, v := range run {
+			if err := s.add(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.endRun(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.runs() != len(runs) {
+		t.Fatalf("spillFile has %d runs, expected %d", s.runs(), len(runs))
+	}
+
+	// Runs can be read in any order.
+	for k := len(runs) - 1; k >= 0; k-- {
+		var got []string
+		dec := s.run(k)
+		for {
+			var v string
+			err := dec.Decode(&v)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, v)
+		}
+		if !reflect.DeepEqual(got, runs[k]) {
+			t.Errorf("run %d returned %q, expected %q", k, got, runs[k])
+		}
+	}
+}
+
+func TestSortLinesSpilled(t *testing.T) {
+	f, err := ioutil.TempFile("", "dtgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.WriteString(strings.Join([]string{
+		"2010-05-01T00:00:05Z line 5",
+		"2010-05-01T00:00:02Z line 2",
+		"  more 2",
+		"2010-05-01T00:00:04Z line 4a",
+		"2010-05-01T00:00:01Z line 1",
+		"2010-05-01T00:00:04Z line 4b",
+		"2010-05-01T00:00:03Z line 3",
+		"",
+	}, "\n"))
+
+	format, _ := retime.New(time.RFC3339, time.UTC)
+	expected := strings.Join([]string{
+		"2010-05-01T00:00:01Z line 1",
+		"2010-05-01T00:00:02Z line 2",
+		"  more 2",
+		"2010-05-01T00:00:03Z line 3",
+		"2010-05-01T00:00:04Z line 4a",
+		"2010-05-01T00:00:04Z line 4b",
+		"2010-05-01T00:00:05Z line 5",
+		"",
+	}, "\n")
+
+	for _, limit := range []int{0, 1, 40, 70} {
+		f.Seek(0, os.SEEK_SET)
+		sorted, err := sortLines(f, Options{multiline: true, limitMemory: limit}, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadAll(sorted)
+		sorted.Close()
+		os.Remove(sorted.Name())
+		if string(content) != expected {
+			t.Errorf("sortLines with limit %d returned %q", limit, content)
+		}
+	}
+}
+
+func TestReverseBufferSpilled(t *testing.T) {
+	for _, v := range []struct {
+		limit, max int
+		expected   string
+	}{
+		{0, 0, "5 4 3 2 more 1"},
+		{1, 0, "5 4 3 2 more 1"},
+		{3, 0, "5 4 3 2 more 1"},
+		{1, 3, "5 4 3"},
+		{3, 4, "5 4 3 2 more"},
+	} {
+		var out bytes.Buffer
+		r := reverseBuffer{limit: v.limit, max: v.max}
+		r.add(&out, "1", "1", "", time.Time{}, false)
+		r.add(&out, "2", "2", "", time.Time{}, false)
+		r.add(&out, "more", "more", "", time.Time{}, true)
+		for _, line := range []string{"3", "4", "5"} {
+			r.add(&out, line, line, "", time.Time{}, false)
+		}
+		r.flush()
+		if got := strings.Join(strings.Fields(out.String()), " "); got != v.expected {
+			t.Errorf("reverseBuffer with limit %d and max %d printed %q", v.limit, v.max, got)
+		}
+	}
+
+	// Spilled lines are printed to their own output.
+	var a, b bytes.Buffer
+	r := reverseBuffer{limit: 1}
+	r.add(&a, "1", "1", "", time.Time{}, false)
+	r.add(&b, "2", "2", "", time.Time{}, false)
+	r.add(&a, "3", "3", "", time.Time{}, false)
+	r.flush()
+	if a.String() != "3\n1\n" || b.String() != "2\n" {
+		t.Errorf("reverseBuffer printed %q and %q", a.String(), b.String())
+	}
+}
@@ -0,0 +1,80 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package main
+
+import (
+	"errors"
+	"io"
+	"log/syslog"
+	"strings"
+)
+
+var facilities = map[string]syslog.Priority{
+	"kern":     syslog.LOG_KERN,
+	"user":     syslog.LOG_USER,
+	"mail":     syslog.LOG_MAIL,
+	"daemon":   syslog.LOG_DAEMON,
+	"auth":     syslog.LOG_AUTH,
+	"syslog":   syslog.LOG_SYSLOG,
+	"lpr":      syslog.LOG_LPR,
+	"news":     syslog.LOG_NEWS,
+	"uucp":     syslog.LOG_UUCP,
+	"cron":     syslog.LOG_CRON,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"ftp":      syslog.LOG_FTP,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
+var severities = map[string]syslog.Priority{
+	"emerg":   syslog.LOG_EMERG,
+	"alert":   syslog.LOG_ALERT,
+	"crit":    syslog.LOG_CRIT,
+	"err":     syslog.LOG_ERR,
+	"warning": syslog.LOG_WARNING,
+	"notice":  syslog.LOG_NOTICE,
+	"info":    syslog.LOG_INFO,
+	"debug":   syslog.LOG_DEBUG,
+}
+
+// parsePriority parses priorities like "local0.notice".
+func parsePriority(s string) (syslog.Priority, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 2 {
+		return 0, errors.New("priority must be of the form FACILITY.SEVERITY")
+	}
+	facility, ok := facilities[parts[0]]
+	if !ok {
+		return 0, errors.New("unknown facility " + parts[0])
+	}
+	severity, ok := severities[parts[1]]
+	if !ok {
+		return 0, errors.New("unknown severity " + parts[1])
+	}
+	return facility | severity, nil
+}
+
+// newSyslogWriter connects to the syslog daemon at address, which is
+// either empty for the local daemon or of the form NETWORK://HOST:PORT.
+func newSyslogWriter(address, priority string) (io.Writer, error) {
+	p, err := parsePriority(priority)
+	if err != nil {
+		return nil, err
+	}
+	var network, raddr string
+	if address != "" {
+		parts := strings.SplitN(address, "://", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("address must be of the form NETWORK://HOST:PORT")
+		}
+		network, raddr = parts[0], parts[1]
+	}
+	return syslog.Dial(network, raddr, p, "dtgrep")
+}
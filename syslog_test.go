@@ -0,0 +1,66 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		priority string
+		value    int
+	}{
+		{"user.info", 14},
+		{"local0.notice", 133},
+		{"kern.emerg", 0},
+	}
+	for _, v := range tests {
+		p, err := parsePriority(v.priority)
+		if err != nil || int(p) != v.value {
+			t.Errorf("parsePriority(%q) returned %d, %v, expected %d", v.priority, p, err, v.value)
+		}
+	}
+
+	for _, v := range []string{"user", "foo.info", "user.foo", "user.info.debug"} {
+		if _, err := parsePriority(v); err == nil {
+			t.Errorf("parsePriority(%q) succeeded", v)
+		}
+	}
+}
+
+func TestSyslogWriter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("Can't listen for syslog messages:", err)
+	}
+	defer conn.Close()
+
+	w, err := newSyslogWriter("udp://"+conn.LocalAddr().String(), "local0.notice")
+	if err != nil {
+		t.Fatal("Can't connect to syslog:", err)
+	}
+
+	fmt.Fprintln(w, "2010-05-01T00:00:00Z line 1")
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal("No syslog message received:", err)
+	}
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<133>") || !strings.HasSuffix(msg, "dtgrep["+fmt.Sprint(os.Getpid())+"]: 2010-05-01T00:00:00Z line 1\n") {
+		t.Errorf("Unexpected syslog message %q", msg)
+	}
+
+	if _, err := newSyslogWriter("localhost:514", "user.info"); err == nil {
+		t.Error("Address without network accepted")
+	}
+}
@@ -6,6 +6,7 @@ name "Empty input results in empty output"
 cat > input <<EOF
 EOF
 
+rc_is 1
 tap go-dategrep --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:01Z" input
 
 #################
@@ -28,6 +29,7 @@ tap go-dategrep --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:03Z" --form
 #################
 name "Match no lines"
 
+rc_is 1
 tap go-dategrep --from "2010-05-01T00:00:03Z" --format rfc3339 input
 
 #################
@@ -49,7 +51,7 @@ foo
 2010-05-01T00:00:02Z line 3
 EOF
 
-rc_is 1
+rc_is 2
 
 stderr_is <<EOF
 Aborting. Found line without date: foo
@@ -66,6 +68,29 @@ EOF
 
 tap go-dategrep --skip-dateless --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input
 
+#################
+name "Write skipped dateless lines to file"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+foo
+2010-05-01T00:00:01Z line 2
+bar
+2010-05-01T00:00:02Z line 3
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+EOF
+
+file_is dateless <<EOF
+foo
+bar
+EOF
+
+tap go-dategrep --skip-dateless --dateless-file dateless --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
 #################
 name "Print multine logs"
 
@@ -95,10 +120,155 @@ stderr_is <<EOF
 Aborting. Found line without date: 2010-05-01T00:00:01Z line 2
 EOF
 
-rc_is 1
+rc_is 2
 
 tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" input
 
+#################
+name "Abort on malformed date"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+2010-02-30T00:00:01Z line 2
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+EOF
+
+stderr_is <<EOF
+Aborting. Found line with malformed date: 2010-02-30T00:00:01Z line 2
+EOF
+
+rc_is 2
+
+tap go-dategrep --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Abort on malformed date with --skip-dateless"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+EOF
+
+stderr_is <<EOF
+Aborting. Found line with malformed date: 2010-02-30T00:00:01Z line 2
+EOF
+
+rc_is 2
+
+tap go-dategrep --skip-dateless --multiline --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Skip malformed date with --on-error skip"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+EOF
+
+tap go-dategrep --on-error skip --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Report dateless line as JSON"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+foo
+2010-05-01T00:00:01Z line 2
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+EOF
+
+stderr_is <<EOF
+{"file":"input","line":2,"text":"foo","error":"no timestamp found"}
+EOF
+
+rc_is 2
+
+tap go-dategrep --error-format json --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Report skipped lines as JSON"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+EOF
+
+stderr_is <<EOF
+{"file":"input","line":2,"text":"foo","error":"Warning: Skipped line without date"}
+EOF
+
+tap go-dategrep --error-format json --on-error warn --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Report errors as JSON"
+
+stderr_is <<EOF
+{"error":"Start date must be before end date."}
+EOF
+
+rc_is 2
+
+tap go-dategrep --error-format json --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:00Z" --format rfc3339 input
+
+#################
+name "Collect errors into a JSON array"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+foo
+bar
+2010-05-01T00:00:01Z line 2
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+EOF
+
+stderr_is <<EOF
+[{"file":"input","line":2,"text":"foo","error":"Warning: Skipped line without date"},{"file":"input","line":3,"text":"bar","error":"Warning: Skipped line without date"}]
+EOF
+
+tap go-dategrep --collect-errors --on-error warn --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Collect errors only as JSON"
+
+rc_is 2
+stderr_is <<EOF
+--collect-errors can only be used with --error-format json.
+EOF
+
+tap go-dategrep --collect-errors --error-format text --format rfc3339 input
+
+#################
+name "Interpret dates without timezone at fixed offset"
+
+cat > input <<EOF
+2010-05-01 01:59:59 line 1
+2010-05-01 02:00:00 line 2
+2010-05-01 02:00:01 line 3
+EOF
+
+stdout_is <<EOF
+2010-05-01 02:00:00 line 2
+EOF
+
+tap go-dategrep --location +02:00 --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:01Z" --format "2006-01-02 15:04:05" input
+
+#################
+name "Interpret dates without timezone at fixed offset without colon"
+
+stdout_is <<EOF
+2010-05-01 02:00:00 line 2
+EOF
+
+tap go-dategrep --location +0200 --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:01Z" --format "2006-01-02 15:04:05" input
+
 #################
 name "Getting format from environment"
 
@@ -115,6 +285,444 @@ tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" input
 unset -v GO_DATEGREP_FORMAT
 
 
+#################
+name "Getting named formats from environment"
+
+cat > input <<EOF
+2010/05/01 00:00:00 line 1
+2010/05/01 00:00:01 line 2
+EOF
+
+stdout_is <<EOF
+2010/05/01 00:00:01 line 2
+EOF
+
+stderr_is <<EOF
+Warning: Skipping malformed format bad
+EOF
+
+export GO_DATEGREP_FORMATS="bad;app=2006/01/02 15:04:05"
+tap go-dategrep --location UTC --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format app input
+unset -v GO_DATEGREP_FORMATS
+
+#################
+name "Duration as ISO 8601"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-02T01:59:59Z line 2
+2010-05-02T02:00:00Z line 3
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-02T01:59:59Z line 2
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:00Z" --duration P1DT2H --format rfc3339 input
+
+#################
+name "Duration as ISO 8601 ending at --to"
+
+stdout_is <<EOF
+2010-05-02T01:59:59Z line 2
+EOF
+
+tap go-dategrep --to "2010-05-02T02:00:00Z" --duration PT1H30M --format rfc3339 input
+
+#################
+name "Named format nginx"
+
+cat > input <<'EOF'
+203.0.113.7 - - [01/May/2010:00:00:00 +0000] "GET / HTTP/1.1" 200 612 "-" "curl/7.68.0"
+203.0.113.7 - - [01/May/2010:00:00:01 +0000] "GET /a HTTP/1.1" 200 612 "-" "curl/7.68.0"
+203.0.113.7 - - [01/May/2010:00:00:02 +0000] "GET /b HTTP/1.1" 404 153 "-" "curl/7.68.0"
+EOF
+
+stdout_is <<'EOF'
+203.0.113.7 - - [01/May/2010:00:00:01 +0000] "GET /a HTTP/1.1" 200 612 "-" "curl/7.68.0"
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format nginx input
+
+#################
+name "Named format nginx from environment"
+
+stdout_is <<'EOF'
+203.0.113.7 - - [01/May/2010:00:00:01 +0000] "GET /a HTTP/1.1" 200 612 "-" "curl/7.68.0"
+EOF
+
+export GO_DATEGREP_FORMAT=nginx
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" input
+unset -v GO_DATEGREP_FORMAT
+
+#################
+name "Named format syslog5424"
+
+cat > input <<'EOF'
+2010-05-01T00:00:00.123456+00:00 host app[42]: line 1
+2010-05-01T00:00:01.5+00:00 host app[42]: line 2
+2010-05-01T02:00:02+02:00 host app[42]: line 3
+EOF
+
+stdout_is <<'EOF'
+2010-05-01T00:00:01.5+00:00 host app[42]: line 2
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format syslog5424 input
+
+#################
+name "Format epoch"
+
+cat > input <<'EOF'
+1272672000 line 1
+1272672001 line 2
+1272672002 line 3
+EOF
+
+stdout_is <<'EOF'
+1272672001 line 2
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format epoch input
+
+#################
+name "Format epoch-ms"
+
+cat > input <<'EOF'
+1272672000999 line 1
+1272672001000 line 2
+1272672001999 line 3
+1272672002000 line 4
+EOF
+
+stdout_is <<'EOF'
+1272672001000 line 2
+1272672001999 line 3
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format epoch-ms input
+
+#################
+name "Numbers within continuation lines are no epoch"
+
+cat > input <<'EOF'
+1272672001 exception
+  at Foo.java:42
+1272672002 line 3
+EOF
+
+stdout_is <<'EOF'
+1272672001 exception
+  at Foo.java:42
+EOF
+
+tap go-dategrep --multiline --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format epoch input
+
+#################
+name "Strftime format"
+
+cat > input <<'EOF'
+2010-05-01 00:00:00 line 1
+2010-05-01 00:00:01 line 2
+2010-05-01 00:00:02 line 3
+EOF
+
+stdout_is <<'EOF'
+2010-05-01 00:00:01 line 2
+EOF
+
+tap go-dategrep --location UTC --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format '%Y-%m-%d %H:%M:%S' input
+
+#################
+name "Strftime format from environment"
+
+stdout_is <<'EOF'
+2010-05-01 00:00:01 line 2
+EOF
+
+export GO_DATEGREP_FORMAT='%F %T'
+tap go-dategrep --location UTC --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" input
+unset -v GO_DATEGREP_FORMAT
+
+#################
+name "Unknown strftime directive"
+
+rc_is 2
+stderr_is <<'EOF'
+Can't create format: unknown strftime directive %Q
+EOF
+
+tap go-dategrep --format '%Y %Q' input
+
+#################
+name "Quiet with matching lines"
+
+cat > input <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+EOF
+
+stdout_is <<'EOF'
+EOF
+
+tap go-dategrep -q --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" input
+
+#################
+name "Quiet without matching lines"
+
+rc_is 1
+stdout_is <<'EOF'
+EOF
+
+tap go-dategrep --quiet --format rfc3339 --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:03Z" input
+
+#################
+name "Set the year of formats without year"
+
+cat > syslog <<'EOF'
+May  1 00:00:00 line 1
+May  1 00:00:01 line 2
+May  1 00:00:02 line 3
+EOF
+
+stdout_is <<'EOF'
+May  1 00:00:01 line 2
+EOF
+
+tap go-dategrep --year 2010 --format rsyslog --location UTC --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" syslog
+
+#################
+name "Invalid year"
+
+rc_is 2
+stderr_is <<'EOF'
+--year must be between 1 and 9999.
+EOF
+
+tap go-dategrep --year -1 --format rsyslog syslog
+
+#################
+name "Year zero"
+
+rc_is 2
+stderr_is <<'EOF'
+--year must be between 1 and 9999.
+EOF
+
+tap go-dategrep --year 0 --format rsyslog syslog
+
+#################
+name "Guess the year relative to --now"
+
+stdout_is <<'EOF'
+May  1 00:00:01 line 2
+EOF
+
+tap go-dategrep --now "2010-06-01T00:00:00Z" --format rsyslog --location UTC --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" syslog
+
+#################
+name "Default --to is --now"
+
+stdout_is <<'EOF'
+May  1 00:00:00 line 1
+May  1 00:00:01 line 2
+EOF
+
+tap go-dategrep --now "2010-05-01T00:00:02Z" --format rsyslog --location UTC syslog
+
+#################
+name "Set now from the environment"
+
+stdout_is <<'EOF'
+May  1 00:00:01 line 2
+EOF
+
+tap sh -c 'GO_DATEGREP_NOW=2010-05-01T00:00:02Z go-dategrep --format rsyslog --location UTC --duration 1s syslog'
+
+#################
+name "Year of dates in December in January"
+
+cat > december <<'EOF'
+Dec 31 23:00:00 old year
+Jan  1 00:30:00 new year
+EOF
+
+stdout_is <<'EOF'
+Dec 31 23:00:00 old year
+EOF
+
+tap go-dategrep --now "2011-01-01T01:00:00Z" --format rsyslog --location UTC --from "2010-12-31T00:00:00Z" --to "2011-01-01T00:00:00Z" december
+
+#################
+name "Relative datespecs"
+
+stdout_is <<'EOF'
+May  1 00:00:01 line 2
+EOF
+
+tap go-dategrep --now "2010-05-01T00:00:03Z" --format rsyslog --location UTC --from "2s ago" --to "-1s" syslog
+
+#################
+name "Relative --from with --duration"
+
+stdout_is <<'EOF'
+May  1 00:00:00 line 1
+May  1 00:00:01 line 2
+EOF
+
+tap go-dategrep --now "2010-05-02T00:00:00Z" --format rsyslog --location UTC --from "1d ago" --duration 2s syslog
+
+#################
+name "Invalid relative datespec"
+
+rc_is 2
+stdout_is <<'EOF'
+EOF
+
+tap sh -c 'go-dategrep --from "2x ago" --format rsyslog syslog 2>/dev/null'
+
+#################
+name "Epoch seconds as datespec"
+
+stdout_is <<'EOF'
+May  1 00:00:01 line 2
+May  1 00:00:02 line 3
+EOF
+
+tap go-dategrep --year 2010 --format rsyslog --location UTC --from @1272672001 --duration 2s syslog
+
+#################
+name "Stop quietly when the reader of the pipe is gone"
+
+awk 'BEGIN { for (i = 0; i < 100000; i++) printf "2010-05-01T00:00:00Z line %d\n", i }' > long
+
+stdout_is <<'EOF'
+2010-05-01T00:00:00Z line 0
+EOF
+stderr_is <<'EOF'
+EOF
+
+tap sh -c 'go-dategrep --format rfc3339 --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:01Z" long | head -n 1'
+
+#################
+name "Only keep matching lines without date"
+
+cat > trace <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z exception
+    at Main.run
+noise
+    at Main.main
+2010-05-01T00:00:02Z line 3
+EOF
+
+stdout_is <<'EOF'
+2010-05-01T00:00:01Z exception
+    at Main.run
+    at Main.main
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 --multiline-regex '^\s+at ' --skip-dateless trace
+
+#################
+name "Abort on other lines without date"
+
+rc_is 2
+stdout_is <<'EOF'
+2010-05-01T00:00:01Z exception
+    at Main.run
+EOF
+stderr_is <<'EOF'
+Aborting. Found line without date: noise
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 --multiline-regex '^\s+at ' trace
+
+#################
+name "Abort on other lines without date while sorting"
+
+rc_is 2
+stdout_is <<'EOF'
+EOF
+stderr_is <<'EOF'
+Aborting. Found line without date: noise
+EOF
+
+tap go-dategrep --pre-sort --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 --multiline-regex '^\s+at ' trace
+
+#################
+name "Invalid multiline regex"
+
+rc_is 2
+stdout_is <<'EOF'
+EOF
+stderr_is <<'EOF'
+Can't compile --multiline-regex: error parsing regexp: missing closing ): `(`
+EOF
+
+tap go-dategrep --format rfc3339 --multiline-regex "(" trace
+
+#################
+name "Warn about lines without date"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:01Z exception
+EOF
+stderr_is <<'EOF'
+Warning: Skipped line without date in trace:     at Main.run
+Warning: Skipped line without date in trace: noise
+Warning: Skipped line without date in trace:     at Main.main
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 --on-error warn trace
+
+#################
+name "Skip lines without date silently"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:01Z exception
+EOF
+stderr_is <<'EOF'
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 --on-error skip trace
+
+#################
+name "Unknown action on errors"
+
+rc_is 2
+stderr_is <<'EOF'
+--on-error must be abort, warn or skip.
+EOF
+
+tap go-dategrep --format rfc3339 --on-error ignore trace
+
+#################
+name "Convert timestamps to another timezone"
+
+cat > zones <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01.5Z line 2
+2010-05-01T00:00:02Z line 3
+EOF
+
+stdout_is <<'EOF'
+2010-04-30T17:00:00-07:00 line 1
+2010-04-30T17:00:01.5-07:00 line 2
+EOF
+
+tap go-dategrep --output-timezone -07:00 --format rfc3339 --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:02Z" zones
+
+#################
+name "Convert timestamps without timezone from --location"
+
+stdout_is <<'EOF'
+May  1 02:00:01 line 2
+EOF
+
+tap go-dategrep --year 2010 --location UTC --output-timezone +02:00 --format "Jan _2 15:04:05" --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" syslog
+
 #################
 
 done_testing
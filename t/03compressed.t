@@ -56,7 +56,82 @@ stdout_is <<EOF
 2010-05-01T00:00:01Z line 2
 EOF
 
-tap "$@" input.gz input
+tap "$@" -h input.gz input
+
+#################
+name "Compressed file before plain file in reverse order"
+
+gzip > input.gz <<EOF
+2010-05-01T00:00:00Z gzip line 1
+2010-05-01T00:00:01Z gzip line 2
+EOF
+
+cat > input <<EOF
+2010-05-01T00:00:02Z plain line 1
+2010-05-01T00:00:03Z plain line 2
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z gzip line 1
+2010-05-01T00:00:01Z gzip line 2
+2010-05-01T00:00:02Z plain line 1
+2010-05-01T00:00:03Z plain line 2
+EOF
+
+tap go-dategrep -h --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:04Z" --format rfc3339 input input.gz
+
+#################
+name "Compressed file entirely before the date range"
+
+stdout_is <<EOF
+2010-05-01T00:00:02Z plain line 1
+2010-05-01T00:00:03Z plain line 2
+EOF
+
+tap go-dategrep -h --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:04Z" --format rfc3339 input input.gz
+
+#################
+name "Plain file entirely after compressed file"
+
+gzip > input.gz <<EOF
+2010-05-01T00:00:04Z gzip line 1
+2010-05-01T00:00:05Z gzip line 2
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:02Z plain line 1
+2010-05-01T00:00:03Z plain line 2
+2010-05-01T00:00:04Z gzip line 1
+EOF
+
+tap go-dategrep -h --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:05Z" --format rfc3339 input.gz input
+
+#################
+name "Uncompress .Z file of compress on the fly"
+
+# compress isn't installed everywhere, so this is the output of compress
+# for three lines.
+printf '\037\235\220\062\140\304\200\321\002\106\215\202\061\250\300\200\241\143\141\103\030\132\100\260\111\343\246\014\210\030\012\002\016\054\170\120\240\102\206\016\005\106\234\130\021\204\214\214\002\011\032\104\370\361\141\103\031\043\051\132\234\241\000' > input.Z
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z line 2
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input.Z
+
+#################
+name "Don't read .Z files as gzip"
+
+gzip > input.Z <<EOF
+2010-05-01T00:00:00Z gzip line 1
+EOF
+
+rc_is 2
+stderr_is <<EOF
+Cannot open input.Z : ncompress: invalid header
+EOF
+
+tap go-dategrep --format rfc3339 input.Z
 
 #################
 done_testing
@@ -13,5 +13,69 @@ tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --form
 2010-05-01T00:00:02Z line 3
 EOF
 
+#################
+name "Lines with equal dates in argument order"
+
+cat > input <<EOF
+2010-05-01T00:00:01Z file line 1
+2010-05-01T00:00:01Z file line 2
+2010-05-01T00:00:02Z file line 3
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z file line 1
+2010-05-01T00:00:01Z file line 2
+2010-05-01T00:00:01Z stdin line 1
+2010-05-01T00:00:02Z file line 3
+2010-05-01T00:00:02Z stdin line 2
+EOF
+
+tap go-dategrep -h --format rfc3339 --to "2010-05-01T00:00:03Z" input - <<EOF
+2010-05-01T00:00:01Z stdin line 1
+2010-05-01T00:00:02Z stdin line 2
+EOF
+
+#################
+name "Lines from stdin first"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z stdin line 1
+2010-05-01T00:00:01Z file line 1
+2010-05-01T00:00:01Z file line 2
+2010-05-01T00:00:02Z stdin line 2
+2010-05-01T00:00:02Z file line 3
+EOF
+
+tap go-dategrep -h --stdin-priority first --format rfc3339 --to "2010-05-01T00:00:03Z" input - <<EOF
+2010-05-01T00:00:01Z stdin line 1
+2010-05-01T00:00:02Z stdin line 2
+EOF
+
+#################
+name "Lines from stdin last"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z file line 1
+2010-05-01T00:00:01Z file line 2
+2010-05-01T00:00:01Z stdin line 1
+2010-05-01T00:00:02Z file line 3
+2010-05-01T00:00:02Z stdin line 2
+EOF
+
+tap go-dategrep -h --stdin-priority last --format rfc3339 --to "2010-05-01T00:00:03Z" - input <<EOF
+2010-05-01T00:00:01Z stdin line 1
+2010-05-01T00:00:02Z stdin line 2
+EOF
+
+#################
+name "Invalid stdin priority without stdin"
+
+rc_is 2
+stderr_is <<EOF
+--stdin-priority must be either first or last.
+EOF
+
+tap go-dategrep --stdin-priority middle --format rfc3339 input
+
 #################
 done_testing
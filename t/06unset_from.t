@@ -0,0 +1,32 @@
+#!tapsig
+
+#################
+name "Unset --from matches from the first line"
+
+i=0
+while [ $i -lt 600 ];do
+	printf '2010-05-01T00:%02d:%02dZ line %d\n' $((i / 60)) $((i % 60)) $i
+	i=$((i + 1))
+done > input
+
+head -n 3 input > stdout_plan
+
+tap go-dategrep --to "2010-05-01T00:00:03Z" --format rfc3339 input
+
+#################
+name "Unset --from and --to match the whole file"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+EOF
+
+tap go-dategrep --format rfc3339 input
+
+#################
+done_testing
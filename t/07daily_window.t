@@ -0,0 +1,70 @@
+#!tapsig
+
+cat > input <<EOF
+2010-05-01T08:00:00Z day 1 before
+2010-05-01T09:00:00Z day 1 start
+2010-05-01T16:59:59Z day 1 end
+2010-05-01T17:00:00Z day 1 after
+2010-05-02T08:59:59Z day 2 before
+2010-05-02T12:00:00Z day 2 noon
+2010-05-02T17:30:00Z day 2 after
+2010-05-03T09:30:00Z day 3 morning
+2010-05-03T15:00:00Z day 3 afternoon
+2010-05-03T18:00:00Z day 3 after
+EOF
+
+#################
+name "Business hours over several days"
+
+stdout_is <<EOF
+2010-05-01T09:00:00Z day 1 start
+2010-05-01T16:59:59Z day 1 end
+2010-05-02T12:00:00Z day 2 noon
+2010-05-03T09:30:00Z day 3 morning
+2010-05-03T15:00:00Z day 3 afternoon
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:00Z" --to "2010-05-04T00:00:00Z" --daily-window 09:00-17:00 --format rfc3339 input
+
+#################
+name "Business hours with partial first and last day"
+
+stdout_is <<EOF
+2010-05-01T16:59:59Z day 1 end
+2010-05-02T12:00:00Z day 2 noon
+2010-05-03T09:30:00Z day 3 morning
+EOF
+
+tap go-dategrep --from "2010-05-01T12:00:00Z" --to "2010-05-03T10:00:00Z" --daily-window 09:00-17:00 --format rfc3339 input
+
+#################
+name "Window spanning midnight"
+
+stdout_is <<EOF
+2010-05-01T08:00:00Z day 1 before
+2010-05-01T17:00:00Z day 1 after
+2010-05-02T08:59:59Z day 2 before
+2010-05-02T17:30:00Z day 2 after
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:00Z" --to "2010-05-03T00:00:00Z" --daily-window 17:00-09:00 --format rfc3339 input
+
+#################
+name "Continuation lines follow their dated line"
+
+cat > input <<EOF
+2010-05-01T08:00:00Z line 1
+foo
+2010-05-01T09:00:00Z line 2
+bar
+EOF
+
+stdout_is <<EOF
+2010-05-01T09:00:00Z line 2
+bar
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:00Z" --to "2010-05-02T00:00:00Z" --daily-window 09:00-17:00 --format rfc3339 --multiline input
+
+#################
+done_testing
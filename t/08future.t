@@ -0,0 +1,43 @@
+#!tapsig
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+2099-05-01T00:00:00Z line 2
+2099-05-01T00:00:01Z line 3
+EOF
+
+#################
+name "Warn about lines dated after now"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+EOF
+
+stderr_is <<EOF
+Warning: Skipped lines dated after now. Use --allow-future to print them.
+EOF
+
+tap go-dategrep --format rfc3339 input
+
+#################
+name "Print lines dated after now"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+2099-05-01T00:00:00Z line 2
+2099-05-01T00:00:01Z line 3
+EOF
+
+tap go-dategrep --allow-future --format rfc3339 input
+
+#################
+name "No warning with explicit --to"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+EOF
+
+tap go-dategrep --to "2011-01-01T00:00:00Z" --format rfc3339 input
+
+#################
+done_testing
@@ -0,0 +1,128 @@
+#!tapsig
+
+#################
+name "Parse rfc1123 with zone abbreviations"
+
+cat > input <<EOF
+Sat, 01 May 2010 09:00:00 EDT line 1
+Sat, 01 May 2010 14:00:00 GMT line 2
+Sat, 01 May 2010 16:00:01 CEST line 3
+Sat, 01 May 2010 07:00:02 PDT line 4
+EOF
+
+stdout_is <<EOF
+Sat, 01 May 2010 14:00:00 GMT line 2
+Sat, 01 May 2010 16:00:01 CEST line 3
+EOF
+
+tap go-dategrep --from "2010-05-01T14:00:00Z" --to "2010-05-01T14:00:02Z" --format rfc1123 input
+
+#################
+name "Parse rfc822 with zone abbreviations"
+
+cat > input <<EOF
+01 May 10 09:00 EDT line 1
+01 May 10 07:01 PDT line 2
+01 May 10 14:02 GMT line 3
+EOF
+
+stdout_is <<EOF
+01 May 10 07:01 PDT line 2
+EOF
+
+tap go-dategrep --from "2010-05-01T14:01:00Z" --to "2010-05-01T14:02:00Z" --format rfc822 input
+
+#################
+name "Parse ansic with space padded day"
+
+cat > input <<EOF
+Sat May  1 00:00:00 2010 line 1
+Sat May  1 00:00:01 2010 line 2
+Sun May  2 00:00:02 2010 line 3
+EOF
+
+stdout_is <<EOF
+Sat May  1 00:00:01 2010 line 2
+Sun May  2 00:00:02 2010 line 3
+EOF
+
+tap go-dategrep --location UTC --from "2010-05-01T00:00:01Z" --to "2010-05-03T00:00:00Z" --format ansic input
+
+#################
+name "Parse unixdate"
+
+cat > input <<EOF
+Sat May  1 00:00:00 UTC 2010 line 1
+Fri Apr 30 20:00:01 EDT 2010 line 2
+Mon May 10 00:00:02 UTC 2010 line 3
+EOF
+
+stdout_is <<EOF
+Fri Apr 30 20:00:01 EDT 2010 line 2
+Mon May 10 00:00:02 UTC 2010 line 3
+EOF
+
+tap go-dategrep --location UTC --from "2010-05-01T00:00:01Z" --to "2010-05-11T00:00:00Z" --format unixdate input
+
+#################
+name "Parse mjd"
+
+cat > input <<EOF
+55317.25 line 1
+55317.5 line 2
+55318.75 line 3
+EOF
+
+stdout_is <<EOF
+55317.5 line 2
+EOF
+
+tap go-dategrep --from "2010-05-01T12:00:00Z" --to "2010-05-02T00:00:00Z" --format mjd input
+
+#################
+name "Parse jd"
+
+cat > input <<EOF
+2455317.75 line 1
+2455318.0 line 2
+2455319.25 line 3
+EOF
+
+stdout_is <<EOF
+2455318.0 line 2
+2455319.25 line 3
+EOF
+
+tap go-dategrep --from "2010-05-01T12:00:00Z" --to "2010-05-03T00:00:00Z" --format jd input
+
+#################
+name "Parse euro"
+
+cat > input <<EOF
+30.04.2010 23:59:59.999 line 1
+01.05.2010 00:00:00.001 line 2
+02.05.2010 00:00:00 line 3
+EOF
+
+stdout_is <<EOF
+01.05.2010 00:00:00.001 line 2
+EOF
+
+tap go-dategrep --location UTC --from "2010-05-01T00:00:00Z" --to "2010-05-02T00:00:00Z" --format euro input
+
+#################
+name "Two-digit years with pivot"
+
+cat > input <<EOF
+01 May 68 00:00 UTC line 1
+01 May 98 00:00 UTC line 2
+EOF
+
+stdout_is <<EOF
+01 May 68 00:00 UTC line 1
+EOF
+
+tap go-dategrep --year-pivot 50 --from "1968-01-01T00:00:00Z" --to "1969-01-01T00:00:00Z" --format rfc822 input
+
+#################
+done_testing
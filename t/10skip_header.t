@@ -0,0 +1,64 @@
+#!tapsig
+
+cat > input <<EOF
+# appliance log
+time,message
+2010-05-01T00:00:00Z,line 1
+2010-05-01T00:00:01Z,line 2
+2010-05-01T00:00:02Z,line 3
+EOF
+
+#################
+name "Abort on header without --auto-skip-header"
+
+rc_is 2
+
+stderr_is <<EOF
+Aborting. Found line without date: # appliance log
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Skip multi-line header"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z,line 1
+2010-05-01T00:00:01Z,line 2
+EOF
+
+tap go-dategrep --auto-skip-header --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Skip header on stdin"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z,line 2
+EOF
+
+tap go-dategrep --auto-skip-header --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 - < input
+
+#################
+name "Abort on dateless line after header"
+
+cat > input <<EOF
+time,message
+2010-05-01T00:00:00Z,line 1
+foo
+2010-05-01T00:00:01Z,line 2
+EOF
+
+rc_is 2
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z,line 1
+EOF
+
+stderr_is <<EOF
+Aborting. Found line without date: foo
+EOF
+
+tap go-dategrep --auto-skip-header --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+done_testing
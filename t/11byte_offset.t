@@ -0,0 +1,24 @@
+#!tapsig
+
+i=0
+while [ $i -lt 600 ];do
+	printf '2010-05-01T00:%02d:%02dZ line %d\n' $((i / 60)) $((i % 60)) $i
+	i=$((i + 1))
+done > input
+
+#################
+name "Print byte offsets of matched lines"
+
+awk '{ print off + 0 ":" $0; off += length($0) + 1 }' input | sed -n '301,303p' > stdout_plan
+
+tap go-dategrep -b --from "2010-05-01T00:05:00Z" --to "2010-05-01T00:05:03Z" --format rfc3339 input
+
+#################
+name "Print byte offsets from the start of the file"
+
+awk '{ print off + 0 ":" $0; off += length($0) + 1 }' input | sed -n '1,2p' > stdout_plan
+
+tap go-dategrep --byte-offset --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+done_testing
@@ -0,0 +1,73 @@
+#!tapsig
+
+# 100 lines with 29 bytes each
+i=0
+while [ $i -lt 100 ];do
+	printf '2010-05-01T00:00:%02dZ line %02d\n' $i $i
+	i=$((i + 1))
+done > input
+
+#################
+name "Print lines within a percentage slice of the file"
+
+# 50% is byte 1450 where line 50 starts, 60% is the start of line 60.
+
+sed -n '51,60p' input > stdout_plan
+
+stderr_is <<EOF
+input : slice spans 2010-05-01T00:00:50Z to 2010-05-01T00:00:59Z
+EOF
+
+tap go-dategrep --from-percent 50 --to-percent 60 --format rfc3339 input
+
+#################
+name "Skip partial line at the start of the slice"
+
+# 50.5% is byte 1464 in the middle of line 50.
+
+sed -n '52,60p' input > stdout_plan
+
+stderr_is <<EOF
+input : slice spans 2010-05-01T00:00:51Z to 2010-05-01T00:00:59Z
+EOF
+
+tap go-dategrep --from-percent 50.5 --to-percent 60 --format rfc3339 input
+
+#################
+name "Slice from the start of the file with byte offsets"
+
+awk '{ print off + 0 ":" $0; off += length($0) + 1 }' input | sed -n '1,4p' > stdout_plan
+
+stderr_is <<EOF
+input : slice spans 2010-05-01T00:00:00Z to 2010-05-01T00:00:03Z
+EOF
+
+tap go-dategrep -b --to-percent 4 --format rfc3339 input
+
+#################
+name "Highlight timestamps of a slice"
+
+stdout_is <<EOF
+$(printf '\033[1;32m2010-05-01T00:00:00Z\033[0m line 00')
+$(printf '\033[1;32m2010-05-01T00:00:01Z\033[0m line 01')
+EOF
+
+stderr_is <<EOF
+input : slice spans 2010-05-01T00:00:00Z to 2010-05-01T00:00:01Z
+EOF
+
+tap go-dategrep --color always --to-percent 2 --format rfc3339 input
+
+#################
+name "Slicing records fails"
+
+rc_is 2
+
+stderr_is <<EOF
+--from-percent and --to-percent can't be used with --record-lines.
+EOF
+
+tap go-dategrep --from-percent 50 --to-percent 52 --record-lines 2 --timestamp-line 2 --format rfc3339 input
+
+#################
+done_testing
@@ -0,0 +1,35 @@
+#!tapsig
+
+printf '2010-05-01 00:00:00 line 1\n2010-05-01  00:00:01 line 2\n2010-05-01\t00:00:02 line 3\n2010-05-01 \t 00:00:03 line 4\n' > input
+
+#################
+name "Abort on misaligned timestamps"
+
+stdout_is <<EOF
+2010-05-01 00:00:00 line 1
+EOF
+
+stderr_is <<EOF
+Aborting. Found line without date: 2010-05-01  00:00:01 line 2
+EOF
+
+rc_is 2
+
+tap go-dategrep --location UTC --to "2010-05-01 00:00:04Z" --format "2006-01-02 15:04:05" input
+
+#################
+name "Normalize whitespace before searching dates"
+
+cp input stdout_plan
+
+tap go-dategrep --normalize-whitespace --location UTC --to "2010-05-01 00:00:04Z" --format "2006-01-02 15:04:05" input
+
+#################
+name "Normalize whitespace keeps printed lines"
+
+printf '2010-05-01\t00:00:02 line 3\n' > stdout_plan
+
+tap go-dategrep --normalize-whitespace --location UTC --from "2010-05-01 00:00:02Z" --to "2010-05-01 00:00:03Z" --format "2006-01-02 15:04:05" input
+
+#################
+done_testing
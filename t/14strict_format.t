@@ -0,0 +1,51 @@
+#!tapsig
+
+cat > input <<EOF
+2010-05-01T00:00:00Z service a line 1
+May 01 00:00:00 service b line 1
+service c started at 2010-05-01T00:00:01Z
+2010-05-01T00:00:01Z service a line 2
+May 01 00:00:02 service b line 2
+2010-05-01T00:00:02Z service a line 3
+EOF
+
+#################
+name "Skip lines of other formats in mixed stream"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z service a line 1
+service c started at 2010-05-01T00:00:01Z
+2010-05-01T00:00:01Z service a line 2
+2010-05-01T00:00:02Z service a line 3
+EOF
+
+tap go-dategrep --skip-dateless --to "2010-05-01T00:00:03Z" --format rfc3339 input
+
+#################
+name "Only accept timestamps at start of line"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z service a line 1
+2010-05-01T00:00:01Z service a line 2
+2010-05-01T00:00:02Z service a line 3
+EOF
+
+tap go-dategrep --strict-format --skip-dateless --to "2010-05-01T00:00:03Z" --format rfc3339 input
+
+#################
+name "Strict format without --skip-dateless"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z service a line 1
+EOF
+
+stderr_is <<EOF
+Aborting. Found line without date: May 01 00:00:00 service b line 1
+EOF
+
+rc_is 2
+
+tap go-dategrep --strict-format --to "2010-05-01T00:00:03Z" --format rfc3339 input
+
+#################
+done_testing
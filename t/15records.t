@@ -0,0 +1,69 @@
+#!tapsig
+
+cat > input <<EOF
+com.example.Foo handle
+2010-05-01T00:00:00Z line 1
+com.example.Bar handle
+2010-05-01T00:00:01Z line 2
+com.example.Baz handle
+2010-05-01T00:00:02Z line 3
+EOF
+
+#################
+name "Print two-line records"
+
+stdout_is <<EOF
+com.example.Bar handle
+2010-05-01T00:00:01Z line 2
+com.example.Baz handle
+2010-05-01T00:00:02Z line 3
+EOF
+
+tap go-dategrep --record-lines 2 --timestamp-line 2 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:03Z" --format rfc3339 input
+
+#################
+name "Ignore dangling final line"
+
+echo "com.example.Qux handle" >> input
+
+stdout_is <<EOF
+com.example.Baz handle
+2010-05-01T00:00:02Z line 3
+EOF
+
+stderr_is <<EOF
+Ignoring incomplete record at end of input
+EOF
+
+tap go-dategrep --record-lines 2 --timestamp-line 2 --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:03Z" --format rfc3339 input
+
+#################
+name "Print incomplete record with timestamp"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+details 1
+2010-05-01T00:00:01Z line 2
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+details 1
+2010-05-01T00:00:01Z line 2
+EOF
+
+tap go-dategrep --record-lines 2 --to "2010-05-01T00:00:03Z" --format rfc3339 input
+
+#################
+name "Timestamp line must be within record"
+
+rc_is 2
+
+stderr_is <<EOF
+--timestamp-line must be between 1 and --record-lines.
+EOF
+
+tap go-dategrep --record-lines 2 --timestamp-line 3 --format rfc3339 input
+
+#################
+done_testing
@@ -0,0 +1,43 @@
+#!tapsig
+
+printf '2010-05-01T00:00:00Z line 1\n2010-05-01T00:00:01Z line\t2\n2010-05-01T00:00:02Z line\\3\n' > input
+
+#################
+name "Print epoch, file name and escaped line"
+
+stdout_is <<'EOF'
+1272672000	input	2010-05-01T00:00:00Z line 1
+1272672001	input	2010-05-01T00:00:01Z line\t2
+1272672002	input	2010-05-01T00:00:02Z line\\3
+EOF
+
+tap go-dategrep --tsv --to "2010-05-01T00:00:03Z" --format rfc3339 input
+
+#################
+name "Every line has three columns"
+
+stdout_is <<EOF
+3
+3
+3
+EOF
+
+tap sh -c 'go-dategrep --tsv --to "2010-05-01T00:00:03Z" --format rfc3339 input | awk -F "\t" "{ print NF }"'
+
+#################
+name "Empty epoch for lines without timestamp"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+foo
+EOF
+
+stdout_is <<EOF
+1272672000	input	2010-05-01T00:00:00Z line 1
+	input	foo
+EOF
+
+tap go-dategrep --tsv --multiline --to "2010-05-01T00:00:03Z" --format rfc3339 input
+
+#################
+done_testing
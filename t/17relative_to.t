@@ -0,0 +1,45 @@
+#!tapsig
+
+i=0
+while [ $i -lt 600 ];do
+	printf '2010-05-01T%02d:%02d:00Z line %d\n' $((i / 60)) $((i % 60)) $i
+	i=$((i + 1))
+done > input
+
+#################
+name "Duration relative to the last line"
+
+tail -n 120 input > stdout_plan
+
+tap go-dategrep --relative-to last --duration 2h --format rfc3339 input
+
+#################
+name "Datespecs relative to the last line"
+
+sed -n '541,570p' input > stdout_plan
+
+tap go-dategrep --relative-to last --from "now truncate 1h" --to "now truncate 1h add 30m" --format rfc3339 input
+
+#################
+name "Relative to last date of several files"
+
+sed -n '1,300p' input > input1
+sed -n '1,120p' input > input2
+
+sed -n '181,300p' input > stdout_plan
+
+tap go-dategrep -h --relative-to last --duration 2h --format rfc3339 input2 input1
+
+#################
+name "Relative to last can't read stdin"
+
+rc_is 2
+
+stderr_is <<EOF
+--relative-to last can't be used with stdin.
+EOF
+
+tap go-dategrep --relative-to last --duration 2h --format rfc3339 < input
+
+#################
+done_testing
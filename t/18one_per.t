@@ -0,0 +1,141 @@
+#!tapsig
+
+cat > input1 <<EOF
+2010-05-01T00:00:05Z file 1 line 1
+2010-05-01T00:00:30Z file 1 line 2
+2010-05-01T00:01:10Z file 1 line 3
+2010-05-01T00:03:00Z file 1 line 4
+EOF
+
+cat > input2 <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:01:00Z file 2 line 2
+2010-05-01T00:01:59Z file 2 line 3
+2010-05-01T00:03:59Z file 2 line 4
+EOF
+
+#################
+name "Print first line per minute"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:01:00Z file 2 line 2
+2010-05-01T00:03:00Z file 1 line 4
+EOF
+
+tap go-dategrep -h --one-per 1m --to "2010-05-01T00:04:00Z" --format rfc3339 input1 input2
+
+#################
+name "Buckets start with the date range"
+
+stdout_is <<EOF
+2010-05-01T00:00:30Z file 1 line 2
+2010-05-01T00:01:00Z file 2 line 2
+2010-05-01T00:03:00Z file 1 line 4
+EOF
+
+tap go-dategrep -h --one-per 1m --from "2010-05-01T00:00:10Z" --to "2010-05-01T00:04:00Z" --format rfc3339 input1 input2
+
+#################
+name "Buckets are aligned to location"
+
+# Kolkata is 5:30 hours ahead of UTC, so its hours start at half past.
+
+cat > input <<EOF
+2010-05-01T00:10:00Z line 1
+2010-05-01T00:29:59Z line 2
+2010-05-01T00:30:00Z line 3
+2010-05-01T00:50:00Z line 4
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:10:00Z line 1
+2010-05-01T00:30:00Z line 3
+EOF
+
+tap go-dategrep --one-per 1h --location Asia/Kolkata --to "2010-05-01T01:00:00Z" --format rfc3339 input
+
+#################
+name "Print last line per minute"
+
+stdout_is <<EOF
+2010-05-01T00:00:30Z file 1 line 2
+2010-05-01T00:01:59Z file 2 line 3
+2010-05-01T00:03:59Z file 2 line 4
+EOF
+
+tap go-dategrep -h --last-per 1m --to "2010-05-01T00:04:00Z" --format rfc3339 input1 input2
+
+#################
+name "Last line per minute keeps its continuation lines"
+
+cat > input <<EOF
+2010-05-01T00:00:10Z line 1
+foo
+2010-05-01T00:00:20Z line 2
+bar
+2010-05-01T00:01:10Z line 3
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:20Z line 2
+bar
+2010-05-01T00:01:10Z line 3
+EOF
+
+tap go-dategrep --last-per 1m --multiline --to "2010-05-01T00:04:00Z" --format rfc3339 input
+
+#################
+name "Print at most two lines per minute"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:05Z file 1 line 1
+2010-05-01T00:01:00Z file 2 line 2
+2010-05-01T00:01:10Z file 1 line 3
+2010-05-01T00:03:00Z file 1 line 4
+2010-05-01T00:03:59Z file 2 line 4
+EOF
+
+tap go-dategrep -h --max-per 1m:2 --to "2010-05-01T00:04:00Z" --format rfc3339 input1 input2
+
+#################
+name "Mark dropped lines"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+... (2 more in this bucket) ...
+2010-05-01T00:01:00Z file 2 line 2
+... (2 more in this bucket) ...
+2010-05-01T00:03:00Z file 1 line 4
+... (1 more in this bucket) ...
+EOF
+
+tap go-dategrep -h --max-per 1m:1 --max-per-marker --to "2010-05-01T00:04:00Z" --format rfc3339 input1 input2
+
+#################
+name "Mark dropped lines in reverse"
+
+stdout_is <<EOF
+... (1 more in this bucket) ...
+2010-05-01T00:03:00Z file 1 line 4
+... (2 more in this bucket) ...
+2010-05-01T00:01:00Z file 2 line 2
+... (2 more in this bucket) ...
+2010-05-01T00:00:01Z file 2 line 1
+EOF
+
+tap go-dategrep -h --max-per 1m:1 --max-per-marker --reverse --to "2010-05-01T00:04:00Z" --format rfc3339 input1 input2
+
+#################
+name "Markers aren't counted"
+
+stdout_is <<EOF
+input1:1
+input2:2
+EOF
+
+tap go-dategrep --count --max-per 1m:1 --max-per-marker --to "2010-05-01T00:04:00Z" --format rfc3339 input1 input2
+
+#################
+done_testing
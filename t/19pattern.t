@@ -0,0 +1,30 @@
+#!tapsig
+
+cat > input <<EOF
+db 2010-01-01 00:00:00 2010-05-01 00:00:00 line 1
+web-42.example.com 2010-01-01 00:00:00 2010-05-01 00:00:01 line 2
+a 2010-01-01 00:00:00 2010-05-01 00:00:02 line 3
+EOF
+
+#################
+name "Search timestamps after variable width fields"
+
+stdout_is <<EOF
+web-42.example.com 2010-01-01 00:00:00 2010-05-01 00:00:01 line 2
+EOF
+
+tap go-dategrep --pattern '^\S+ \S+ \S+ (?P<ts>\d{4}-\d\d-\d\d \d\d:\d\d:\d\d) ' --location UTC --from "2010-05-01 00:00:01Z" --to "2010-05-01 00:00:02Z" --format "2006-01-02 15:04:05" input
+
+#################
+name "Invalid pattern"
+
+rc_is 2
+
+stderr_is <<EOF
+Can't compile pattern: error parsing regexp: missing closing ): \`(\`
+EOF
+
+tap go-dategrep --pattern '(' --format "2006-01-02 15:04:05" input
+
+#################
+done_testing
@@ -0,0 +1,219 @@
+#!tapsig
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:10Z line 2
+2010-05-01T00:00:05Z line 3
+foo
+2010-05-01T00:00:06Z line 5
+2010-05-01T00:00:01Z line 6
+2010-05-01T00:00:20Z line 7
+EOF
+
+#################
+name "Warn about clock regressions"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:10Z line 2
+2010-05-01T00:00:05Z line 3
+foo
+2010-05-01T00:00:06Z line 5
+2010-05-01T00:00:01Z line 6
+2010-05-01T00:00:20Z line 7
+EOF
+
+stderr_is <<EOF
+Warning: Clock regression in input line 3: 5s before previous line
+Warning: Clock regression in input line 6: 5s before previous line
+EOF
+
+tap go-dategrep --warn-on-regression --multiline --to "2010-05-01T00:00:30Z" --format rfc3339 input
+
+#################
+name "Warn about clock regressions on stdin"
+
+stdout_is <<EOF
+2010-05-01T00:00:20Z line 7
+EOF
+
+stderr_is <<EOF
+Warning: Clock regression in - line 3: 5s before previous line
+Warning: Clock regression in - line 6: 5s before previous line
+EOF
+
+tap go-dategrep --warn-on-regression --skip-dateless --from "2010-05-01T00:00:11Z" --to "2010-05-01T00:00:30Z" --format rfc3339 - < input
+
+#################
+name "Report clock regressions in words"
+
+stdout_is <<EOF
+2010-05-01T00:00:20Z line 7
+EOF
+
+stderr_is <<EOF
+Warning: Clock regression in input line 3: 5 seconds before previous line
+Warning: Clock regression in input line 6: 5 seconds before previous line
+EOF
+
+tap go-dategrep --human --warn-on-regression --skip-dateless --from "2010-05-01T00:00:11Z" --to "2010-05-01T00:00:30Z" --format rfc3339 input
+
+#################
+name "Strictly increasing dates pass"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:02Z line 3
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:02Z line 3
+EOF
+
+tap go-dategrep --strict-sorted --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:30Z" --format rfc3339 input
+
+#################
+name "Abort on duplicate date"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:01Z line 3
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+EOF
+
+stderr_is <<EOF
+Aborting. Timestamps not increasing in input line 3: 2010-05-01T00:00:01Z after 2010-05-01T00:00:01Z
+EOF
+
+rc_is 2
+
+tap go-dategrep --strict-sorted --to "2010-05-01T00:00:30Z" --format rfc3339 input
+
+#################
+name "Abort on backward date before the date range"
+
+cat > input <<EOF
+2010-05-01T00:00:05Z line 1
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:10Z line 3
+EOF
+
+stderr_is <<EOF
+Aborting. Timestamps not increasing in input line 2: 2010-05-01T00:00:01Z after 2010-05-01T00:00:05Z
+EOF
+
+rc_is 2
+
+tap go-dategrep --strict-sorted --from "2010-05-01T00:00:10Z" --to "2010-05-01T00:00:30Z" --format rfc3339 input
+
+#################
+name "Warn about a skewed date"
+
+cat > input <<EOF
+2010-05-01T00:00:01Z line 1
+2010-05-03T00:00:02Z line 2
+2010-05-01T00:00:03Z line 3
+2010-05-01T00:00:04Z line 4
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z line 1
+EOF
+
+stderr_is <<EOF
+Warning: Suspect date in input line 2: 48h0m1s away from previous line
+EOF
+
+tap go-dategrep --max-skew 1h --to "2010-05-01T00:00:30Z" --format rfc3339 input
+
+#################
+name "Exclude a skewed date"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z line 1
+2010-05-01T00:00:03Z line 3
+2010-05-01T00:00:04Z line 4
+EOF
+
+stderr_is <<EOF
+Warning: Suspect date in input line 2: 48h0m1s away from previous line
+EOF
+
+tap go-dategrep --max-skew 1h --exclude-skewed --to "2010-05-01T00:00:30Z" --format rfc3339 input
+
+#################
+name "A jump of the clock isn't skewed"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+2010-05-01T05:00:00Z line 3
+2010-05-01T05:00:01Z line 4
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+2010-05-01T05:00:00Z line 3
+2010-05-01T05:00:01Z line 4
+EOF
+
+stderr_is <<'EOF'
+EOF
+
+tap go-dategrep --max-skew 1h --exclude-skewed --to "2010-05-02T00:00:00Z" --format rfc3339 input
+
+#################
+name "A skewed first line is compared with the next lines"
+
+cat > input <<EOF
+1970-01-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:02Z line 3
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:02Z line 3
+EOF
+
+stderr_is <<EOF
+Warning: Suspect date in input line 1: 353520h0m1s away from next line
+EOF
+
+tap go-dategrep --max-skew 1h --exclude-skewed --to "2010-05-02T00:00:00Z" --format rfc3339 input
+
+#################
+name "Exclude the continuation lines of a skewed date"
+
+cat > input <<EOF
+2010-05-01T00:00:01Z line 1
+continuation 1
+1970-01-01T00:00:00Z line 2
+continuation 2
+2010-05-01T00:00:03Z line 3
+continuation 3
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z line 1
+continuation 1
+2010-05-01T00:00:03Z line 3
+continuation 3
+EOF
+
+stderr_is <<EOF
+Warning: Suspect date in input line 3: 353520h0m1s away from previous line
+EOF
+
+tap go-dategrep --max-skew 1h --exclude-skewed --multiline --to "2010-05-02T00:00:00Z" --format rfc3339 input
+
+#################
+done_testing
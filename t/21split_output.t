@@ -0,0 +1,51 @@
+#!tapsig
+
+mkdir -p a b
+
+cat > a/input <<EOF
+2010-05-01T00:00:00Z file 1 line 1
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+cat > b/input <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:05Z file 2 line 3
+EOF
+
+#################
+name "Write lines of each file to its own file"
+
+file_is input.matched <<EOF
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+file_is input.2.matched <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+EOF
+
+tap go-dategrep --split-output-dir . --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:05Z" --format rfc3339 a/input b/input
+
+#################
+name "Create no file for files without matches"
+
+stdout_is <<EOF
+input.2.matched
+EOF
+
+tap sh -c 'go-dategrep --split-output-dir lazy --from "2010-05-01T00:00:05Z" --to "2010-05-01T00:00:06Z" --format rfc3339 a/input b/input && ls lazy'
+
+#################
+name "Create output directory"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z file 1 line 1
+EOF
+
+tap sh -c 'go-dategrep --split-output-dir out/new --to "2010-05-01T00:00:01Z" --format rfc3339 a/input && cat out/new/input.matched'
+
+#################
+done_testing
@@ -0,0 +1,38 @@
+#!tapsig
+
+cat > input <<'EOF'
+id,message,time,note
+1,"created 2009-01-01 00:00:00, by admin",2010-05-01 00:00:00,ok
+2,"moved, ""fast""",2010-05-01 00:00:01,"a, b"
+3,plain,"2010-05-01 00:00:02","2011-01-01 00:00:00"
+EOF
+
+#################
+name "Search timestamp in CSV field"
+
+stdout_is <<'EOF'
+2,"moved, ""fast""",2010-05-01 00:00:01,"a, b"
+3,plain,"2010-05-01 00:00:02","2011-01-01 00:00:00"
+EOF
+
+tap go-dategrep --csv --csv-field 3 --auto-skip-header --location UTC --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:03Z" --format "2006-01-02 15:04:05" input
+
+#################
+name "Lines without field are dateless"
+
+cat > input <<'EOF'
+1,a,2010-05-01 00:00:00
+2,b
+3,"c,2010-05-01 00:00:01
+4,d,2010-05-01 00:00:02
+EOF
+
+stdout_is <<'EOF'
+1,a,2010-05-01 00:00:00
+4,d,2010-05-01 00:00:02
+EOF
+
+tap go-dategrep --csv --csv-field 3 --skip-dateless --location UTC --to "2010-05-01T00:00:03Z" --format "2006-01-02 15:04:05" input
+
+#################
+done_testing
@@ -0,0 +1,88 @@
+#!tapsig
+
+#################
+name "Search a shuffled file"
+
+cat > input <<EOF
+2010-05-01T00:00:03Z line 3
+2010-05-01T00:00:01Z line 1
+2010-05-01T00:00:05Z line 5
+2010-05-01T00:00:02Z line 2
+2010-05-01T00:00:04Z line 4
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:02Z line 2
+2010-05-01T00:00:03Z line 3
+2010-05-01T00:00:04Z line 4
+EOF
+
+tap go-dategrep --pre-sort --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:05Z" --format rfc3339 input
+
+#################
+name "Search a large reversed file"
+
+i=600
+while [ $i -gt 0 ];do
+	i=$((i - 1))
+	printf '2010-05-01T00:%02d:%02dZ line %d\n' $((i / 60)) $((i % 60)) $i
+done > input
+
+stdout_is <<EOF
+2010-05-01T00:05:00Z line 300
+2010-05-01T00:05:01Z line 301
+2010-05-01T00:05:02Z line 302
+EOF
+
+tap go-dategrep --pre-sort --from "2010-05-01T00:05:00Z" --to "2010-05-01T00:05:03Z" --format rfc3339 input
+
+#################
+name "Continuation lines stay with their dated line"
+
+cat > input <<EOF
+2010-05-01T00:00:03Z line 3
+foo
+2010-05-01T00:00:01Z line 1
+2010-05-01T00:00:02Z line 2
+bar
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:02Z line 2
+bar
+2010-05-01T00:00:03Z line 3
+foo
+EOF
+
+tap go-dategrep --pre-sort --multiline --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:05Z" --format rfc3339 input
+
+#################
+name "Temporary file is removed"
+
+TMPDIR=$PWD/tmp
+export TMPDIR
+mkdir tmp
+
+cat > input <<EOF
+2010-05-01T00:00:02Z line 2
+2010-05-01T00:00:01Z line 1
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z line 1
+0
+EOF
+
+tap sh -c 'go-dategrep --pre-sort --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input; ls tmp | wc -l'
+
+#################
+name "Temporary file is removed on errors"
+
+stdout_is <<EOF
+0
+EOF
+
+tap sh -c 'go-dategrep --pager never --pre-sort --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input > /dev/full 2>/dev/null; ls tmp | wc -l'
+
+#################
+done_testing
@@ -0,0 +1,104 @@
+#!tapsig
+
+TZ=UTC
+export TZ
+
+cat > input <<EOF
+2010-05-01T23:59:59Z line 1
+2010-05-02T00:00:00Z line 2
+2010-05-02T15:04:00Z line 3
+2010-05-03T00:00:00Z line 4
+EOF
+
+#################
+name "Dates without time start at midnight"
+
+stdout_is <<EOF
+2010-05-02T00:00:00Z line 2
+2010-05-02T15:04:00Z line 3
+EOF
+
+tap go-dategrep --location UTC --from-date 2010-05-02 --to-date 2010-05-03 --format rfc3339 input
+
+#################
+name "Dates with time"
+
+stdout_is <<EOF
+2010-05-02T15:04:00Z line 3
+EOF
+
+tap go-dategrep --location UTC --from-date 2010-05-02 --from-time 15:04 --to-date 2010-05-02 --to-time 15:04:01 --format rfc3339 input
+
+#################
+name "Date and time mixed with datespecs"
+
+stdout_is <<EOF
+2010-05-02T00:00:00Z line 2
+EOF
+
+tap go-dategrep --location UTC --from-date 2010-05-02 --to "2010-05-02T15:04:00Z" --format rfc3339 input
+
+#################
+name "Time without date is today"
+
+cat > input <<EOF
+2010-05-02T00:00:02Z line 1
+2010-05-03T00:00:00Z line 2
+2010-05-03T00:00:02Z line 3
+EOF
+
+stdout_is <<EOF
+2010-05-03T00:00:00Z line 2
+EOF
+
+tap go-dategrep --location UTC --now "2010-05-03T12:00:00Z" --from-time 00:00 --to-time 00:00:01 --format rfc3339 input
+
+#################
+name "Date parts conflict with datespecs"
+
+stderr_is <<EOF
+--from can't be used with --from-date or --from-time.
+EOF
+rc_is 2
+
+tap go-dategrep --from "2010-05-02T00:00:00Z" --from-date 2010-05-02 --format rfc3339 input
+
+#################
+name "Lines around a date"
+
+cat > around <<'EOF'
+2010-05-01T11:49:59Z line 1
+2010-05-01T11:50:00Z line 2
+2010-05-01T12:09:59Z line 3
+2010-05-01T12:10:00Z line 4
+EOF
+
+stdout_is <<'EOF'
+2010-05-01T11:50:00Z line 2
+2010-05-01T12:09:59Z line 3
+EOF
+
+tap go-dategrep --format rfc3339 --around "2010-05-01 12:00" --duration 10m around
+
+#################
+name "--around can't be used with --from"
+
+rc_is 2
+stderr_is <<'EOF'
+--around can't be used with --from or --to.
+EOF
+
+tap go-dategrep --format rfc3339 --around "2010-05-01 12:00" --from "2010-05-01 11:00" --duration 10m around
+
+#################
+name "--around needs --duration"
+
+rc_is 2
+stderr_is <<'EOF'
+--around needs a positive --duration.
+EOF
+
+tap go-dategrep --format rfc3339 --around "2010-05-01 12:00" around
+
+#################
+done_testing
@@ -0,0 +1,92 @@
+#!tapsig
+
+i=0
+while [ $i -lt 600 ];do
+	printf '2010-05-01T00:%02d:%02dZ line %d\n' $((i / 60)) $((i % 60)) $i
+	i=$((i + 1))
+done > input
+
+#################
+name "Prefix with file and line number"
+
+stdout_is <<EOF
+input:301: 2010-05-01T00:05:00Z line 300
+input:302: 2010-05-01T00:05:01Z line 301
+EOF
+
+tap go-dategrep --output-prefix '{file}:{line}: ' --from "2010-05-01T00:05:00Z" --to "2010-05-01T00:05:02Z" --format rfc3339 input
+
+#################
+name "Prefix with timestamp and epoch"
+
+cat > input <<EOF
+01/May/2010:02:00:00 +0200 line 1
+01/May/2010:02:00:01 +0200 line 2
+EOF
+
+stdout_is <<EOF
+2010-05-01T02:00:01+02:00 1272672001 01/May/2010:02:00:01 +0200 line 2
+EOF
+
+tap go-dategrep --output-prefix '{ts} {epoch} ' --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format apache input
+
+#################
+name "Continuation lines are not prefixed"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+foo
+2010-05-01T00:00:01Z line 2
+EOF
+
+stdout_is <<EOF
+1: 2010-05-01T00:00:00Z line 1
+foo
+3: 2010-05-01T00:00:01Z line 2
+EOF
+
+tap go-dategrep --multiline --output-prefix '{line}: ' --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Prefix continuation lines"
+
+stdout_is <<EOF
+1 2010-05-01T00:00:00Z: 2010-05-01T00:00:00Z line 1
+2 : foo
+3 2010-05-01T00:00:01Z: 2010-05-01T00:00:01Z line 2
+EOF
+
+tap go-dategrep --multiline --prefix-continuation --output-prefix '{line} {ts}: ' --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Prefix lines without date"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+>> foo
+2010-05-01T00:00:01Z line 2
+EOF
+
+tap go-dategrep --multiline --dateless-prefix '>> ' --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Lines without date inherit the previous date"
+
+cat > input <<EOF
+01/May/2010:02:00:00 +0200 line 1
+foo
+bar
+01/May/2010:02:00:01 +0200 line 2
+EOF
+
+stdout_is <<EOF
+01/May/2010:02:00:00 +0200 line 1
+> 2010-05-01T02:00:00+02:00 foo
+> 2010-05-01T02:00:00+02:00 bar
+01/May/2010:02:00:01 +0200 line 2
+EOF
+
+tap go-dategrep --multiline --dateless-inherit-timestamp --dateless-prefix '> ' --to "2010-05-01T00:00:02Z" --format apache input
+
+#################
+done_testing
@@ -0,0 +1,55 @@
+#!tapsig
+
+cat > input <<EOF
+2010-05-01T14:59:59Z line 1
+2010-05-01T15:03:59Z line 2
+2010-05-01T15:04:00Z line 3
+2010-05-01T15:04:59Z line 4
+2010-05-01T15:05:00Z line 5
+2010-05-01T15:59:59Z line 6
+2010-05-01T16:00:00Z line 7
+EOF
+
+#################
+name "Minute granularity includes the whole last minute"
+
+stdout_is <<EOF
+2010-05-01T15:04:00Z line 3
+2010-05-01T15:04:59Z line 4
+EOF
+
+tap go-dategrep --granularity minute --from "2010-05-01T15:04:30Z" --to "2010-05-01T15:04:00Z" --format rfc3339 input
+
+#################
+name "Hour granularity"
+
+stdout_is <<EOF
+2010-05-01T15:03:59Z line 2
+2010-05-01T15:04:00Z line 3
+2010-05-01T15:04:59Z line 4
+2010-05-01T15:05:00Z line 5
+2010-05-01T15:59:59Z line 6
+EOF
+
+tap go-dategrep --granularity hour --from "2010-05-01T15:30:00Z" --to "2010-05-01T15:00:00Z" --format rfc3339 input
+
+#################
+name "Second granularity ignores fractions of the bounds"
+
+stdout_is <<EOF
+2010-05-01T14:59:59Z line 1
+EOF
+
+tap go-dategrep --granularity second --from "2010-05-01T14:59:59.7Z" --to "2010-05-01T14:59:59.2Z" --format rfc3339 input
+
+#################
+name "Without granularity --to is exclusive"
+
+stdout_is <<EOF
+2010-05-01T15:03:59Z line 2
+EOF
+
+tap go-dategrep --from "2010-05-01T15:00:00Z" --to "2010-05-01T15:04:00Z" --format rfc3339 input
+
+#################
+done_testing
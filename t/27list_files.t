@@ -0,0 +1,37 @@
+#!tapsig
+
+mkdir logs
+touch logs/app.log logs/app.log.1.gz logs/app.log.2.bz2 logs/other.txt
+
+#################
+name "List files matched by a glob"
+
+stdout_is <<EOF
+plain	logs/app.log
+gzip	logs/app.log.1.gz
+bzip2	logs/app.log.2.bz2
+EOF
+
+tap go-dategrep --list-files 'logs/app.log*'
+
+#################
+name "List stdin and plain arguments"
+
+stdout_is <<EOF
+stdin	-
+plain	logs/other.txt
+EOF
+
+tap go-dategrep --list-files - logs/other.txt
+
+#################
+name "List stdin without arguments"
+
+stdout_is <<EOF
+stdin	-
+EOF
+
+tap go-dategrep --list-files
+
+#################
+done_testing
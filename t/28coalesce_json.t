@@ -0,0 +1,33 @@
+#!tapsig
+
+cat > input <<'EOF'
+2010-05-01T00:00:00Z INFO starting
+2010-05-01T00:00:01Z ERROR "request" failed
+java.lang.IllegalStateException: boom
+	at com.example.Foo.<init>(Foo.java:12)
+	at com.example.Main.main(Main.java:5)
+2010-05-01T00:00:02Z INFO done
+EOF
+
+#################
+name "Stack trace as one JSON object"
+
+stdout_is <<'EOF'
+{"file":"input","time":"2010-05-01T00:00:01Z","message":"2010-05-01T00:00:01Z ERROR \"request\" failed\njava.lang.IllegalStateException: boom\n\tat com.example.Foo.<init>(Foo.java:12)\n\tat com.example.Main.main(Main.java:5)"}
+EOF
+
+tap go-dategrep --coalesce-multiline-into-json --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Every dated line is one JSON object"
+
+stdout_is <<'EOF'
+{"file":"input","time":"2010-05-01T00:00:00Z","message":"2010-05-01T00:00:00Z INFO starting"}
+{"file":"input","time":"2010-05-01T00:00:01Z","message":"2010-05-01T00:00:01Z ERROR \"request\" failed\njava.lang.IllegalStateException: boom\n\tat com.example.Foo.<init>(Foo.java:12)\n\tat com.example.Main.main(Main.java:5)"}
+{"file":"input","time":"2010-05-01T00:00:02Z","message":"2010-05-01T00:00:02Z INFO done"}
+EOF
+
+tap go-dategrep --coalesce-multiline-into-json --to "2010-05-01T00:00:03Z" --format rfc3339 input
+
+#################
+done_testing
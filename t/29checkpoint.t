@@ -0,0 +1,52 @@
+#!tapsig
+
+i=0
+while [ $i -lt 600 ];do
+	printf '2010-05-01T00:%02d:%02dZ line %d\n' $((i / 60)) $((i % 60)) $i
+	i=$((i + 1))
+done > input
+
+#################
+name "First run starts at --from"
+
+stdout_is <<EOF
+2010-05-01T00:05:00Z line 300
+2010-05-01T00:05:01Z line 301
+EOF
+
+tap go-dategrep --checkpoint cp --merge-limit 2 --from "2010-05-01T00:05:00Z" --to "2010-05-01T00:05:05Z" --format rfc3339 input
+
+#################
+name "Restart resumes after the last line"
+
+stdout_is <<EOF
+2010-05-01T00:05:02Z line 302
+2010-05-01T00:05:03Z line 303
+2010-05-01T00:05:04Z line 304
+EOF
+
+tap go-dategrep --checkpoint cp --from "2010-05-01T00:05:00Z" --to "2010-05-01T00:05:05Z" --format rfc3339 input
+
+#################
+name "Restart with a later end"
+
+stdout_is <<EOF
+2010-05-01T00:05:05Z line 305
+2010-05-01T00:05:06Z line 306
+EOF
+
+tap go-dategrep --checkpoint cp --from "2010-05-01T00:05:00Z" --to "2010-05-01T00:05:07Z" --format rfc3339 input
+
+#################
+name "Checkpoint of another file is ignored"
+
+cp input input2
+
+stdout_is <<EOF
+2010-05-01T00:05:00Z line 300
+EOF
+
+tap go-dategrep --checkpoint cp --from "2010-05-01T00:05:00Z" --to "2010-05-01T00:05:01Z" --format rfc3339 input2
+
+#################
+done_testing
@@ -0,0 +1,47 @@
+#!tapsig
+
+cat > input1 <<EOF
+2010-05-01T00:00:01Z seq=2 file 1
+2010-05-01T00:00:01Z seq=5 file 1
+2010-05-01T00:00:02Z seq=7 file 1
+EOF
+
+cat > input2 <<EOF
+2010-05-01T00:00:01Z seq=1 file 2
+2010-05-01T00:00:01Z seq=3 file 2
+2010-05-01T00:00:01Z seq=4 file 2
+2010-05-01T00:00:02Z seq=6 file 2
+EOF
+
+#################
+name "Equal dates are ordered by the secondary key"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z seq=1 file 2
+2010-05-01T00:00:01Z seq=2 file 1
+2010-05-01T00:00:01Z seq=3 file 2
+2010-05-01T00:00:01Z seq=4 file 2
+2010-05-01T00:00:01Z seq=5 file 1
+2010-05-01T00:00:02Z seq=6 file 2
+2010-05-01T00:00:02Z seq=7 file 1
+EOF
+
+tap go-dategrep -h --secondary-key 'seq=(?P<key>\d+)' --to "2010-05-01T00:00:03Z" --format rfc3339 input1 input2
+
+#################
+name "Without secondary key equal dates are in argument order"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z seq=2 file 1
+2010-05-01T00:00:01Z seq=5 file 1
+2010-05-01T00:00:01Z seq=1 file 2
+2010-05-01T00:00:01Z seq=3 file 2
+2010-05-01T00:00:01Z seq=4 file 2
+2010-05-01T00:00:02Z seq=7 file 1
+2010-05-01T00:00:02Z seq=6 file 2
+EOF
+
+tap go-dategrep -h --to "2010-05-01T00:00:03Z" --format rfc3339 input1 input2
+
+#################
+done_testing
@@ -0,0 +1,70 @@
+#!tapsig
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:02Z line 3
+EOF
+
+cat > pager <<'EOF'
+#!/bin/sh
+sed 's/^/paged: /'
+EOF
+chmod +x pager
+
+cat > quitting-pager <<'EOF'
+#!/bin/sh
+head -n 1
+EOF
+chmod +x quitting-pager
+
+i=0
+while [ $i -lt 20000 ];do
+	printf '2010-05-01T00:00:00Z line %d\n' $i
+	i=$((i + 1))
+done > large
+
+#################
+name "Print through the pager"
+
+stdout_is <<EOF
+paged: 2010-05-01T00:00:00Z line 1
+paged: 2010-05-01T00:00:01Z line 2
+EOF
+
+PAGER="$PWD/pager" tap go-dategrep --pager always --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "No pager if stdout is no terminal"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+EOF
+
+PAGER="$PWD/pager" tap go-dategrep --to "2010-05-01T00:00:02Z" --format rfc3339 input
+
+#################
+name "Missing pager"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 1
+EOF
+
+stderr_is <<EOF
+Warning: Can't start pager: $PWD/missing not found
+EOF
+
+PAGER="$PWD/missing" tap go-dategrep --pager always --to "2010-05-01T00:00:01Z" --format rfc3339 input
+
+#################
+name "Pager quit early"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z line 0
+EOF
+
+PAGER="$PWD/quitting-pager" tap go-dategrep --pager always --to "2010-05-01T00:00:01Z" --format rfc3339 large
+
+#################
+done_testing
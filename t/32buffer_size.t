@@ -0,0 +1,42 @@
+#!tapsig
+
+long=$(awk 'BEGIN { while (i++ < 100000) printf "x" }')
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z $long
+2010-05-01T00:00:02Z line 3
+EOF
+
+#################
+name "Read lines longer than 64KB"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z $long
+2010-05-01T00:00:02Z line 3
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:03Z" --format rfc3339 input
+
+#################
+name "Read long lines from stdin"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z $long
+EOF
+
+tap sh -c 'go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 < input'
+
+#################
+name "Lines longer than --buffer-size"
+
+stderr_is <<EOF
+Error finding dates in  input : line longer than --buffer-size
+EOF
+
+rc_is 2
+
+tap go-dategrep --buffer-size 1000 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:03Z" --format rfc3339 input
+
+#################
+done_testing
@@ -0,0 +1,70 @@
+#!tapsig
+
+mkdir logs
+
+cat > logs/www.access.log <<EOF
+01/May/2010:02:00:00 +0200 access line 1
+01/May/2010:02:00:02 +0200 access line 2
+EOF
+
+cat > logs/app.log <<EOF
+2010-05-01T00:00:00Z app line 1
+2010-05-01T00:00:04Z app line 2
+EOF
+
+#################
+name "Choose formats by file name"
+
+stdout_is <<EOF
+01/May/2010:02:00:00 +0200 access line 1
+2010-05-01T00:00:00Z app line 1
+01/May/2010:02:00:02 +0200 access line 2
+2010-05-01T00:00:04Z app line 2
+EOF
+
+tap go-dategrep -h --format-by-name --location UTC --to "2010-05-01T00:00:05Z" --format rfc3339 logs/www.access.log logs/app.log
+
+#################
+name "Rules from the environment come first"
+
+stdout_is <<EOF
+2010-05-01T00:00:00Z app line 1
+01/May/2010:02:00:00 +0200 access line 1
+01/May/2010:02:00:02 +0200 access line 2
+EOF
+
+export GO_DATEGREP_FORMAT_RULES="app.*=rfc3339;*.access.log=apache"
+tap go-dategrep -h --format-by-name --location UTC --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:04Z" --format "2006" 'logs/*'
+unset -v GO_DATEGREP_FORMAT_RULES
+
+#################
+name "Choose formats with --format-for"
+
+stdout_is <<EOF
+01/May/2010:02:00:02 +0200 access line 2
+2010-05-01T00:00:04Z app line 2
+EOF
+
+tap go-dategrep -h --format-for 'www.*=apache' --format-for 'app.log=rfc3339' --location UTC --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:05Z" --format "2006" logs/www.access.log logs/app.log
+
+#################
+name "--format-for comes before --format-by-name"
+
+rc_is 2
+stderr_is <<EOF
+Aborting. Found line without date: 01/May/2010:02:00:00 +0200 access line 1
+EOF
+
+tap go-dategrep --format-for '*access.log=rfc3339' --format-by-name --location UTC --to "2010-05-01T00:00:05Z" logs/www.access.log
+
+#################
+name "Malformed --format-for"
+
+rc_is 2
+stdout_is <<EOF
+EOF
+
+tap sh -c 'go-dategrep --format-for apache logs/app.log 2>/dev/null'
+
+#################
+done_testing
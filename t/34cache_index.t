@@ -0,0 +1,43 @@
+#!tapsig
+
+i=0
+while [ $i -lt 600 ];do
+	printf '2010-05-01T00:%02d:%02dZ line %d\n' $((i / 60)) $((i % 60)) $i
+	i=$((i + 1))
+done > input
+
+#################
+name "Build the index on the first run"
+
+stdout_is <<EOF
+2010-05-01T00:05:00Z line 300
+2010-05-01T00:05:01Z line 301
+1
+EOF
+
+tap sh -c 'go-dategrep --cache-index cache --from "2010-05-01T00:05:00Z" --to "2010-05-01T00:05:02Z" --format rfc3339 input; ls cache | wc -l'
+
+#################
+name "Reuse the index"
+
+stdout_is <<EOF
+2010-05-01T00:09:58Z line 598
+2010-05-01T00:09:59Z line 599
+EOF
+
+tap go-dategrep --cache-index cache --from "2010-05-01T00:09:58Z" --to "2010-05-01T00:10:00Z" --format rfc3339 input
+
+#################
+name "Outdated index is rebuilt"
+
+printf '2010-05-01T00:10:00Z line 600\n' >> input
+
+stdout_is <<EOF
+2010-05-01T00:09:59Z line 599
+2010-05-01T00:10:00Z line 600
+EOF
+
+tap go-dategrep --cache-index cache --from "2010-05-01T00:09:59Z" --to "2010-05-01T00:10:01Z" --format rfc3339 input
+
+#################
+done_testing
@@ -0,0 +1,39 @@
+#!tapsig
+
+#################
+name "List named formats sorted by name"
+
+stdout_is <<'EOF'
+ansic	Mon Jan _2 15:04:05 2006
+apache	02/Jan/2006:15:04:05 -0700
+epoch	seconds since the Unix epoch
+epoch-ms	milliseconds since the Unix epoch
+epoch-ns	nanoseconds since the Unix epoch
+euro	02.01.2006 15:04:05
+jd	Julian Date like 2455318.0
+mjd	Modified Julian Date like 55317.5
+nginx	[02/Jan/2006:15:04:05 -0700]
+rfc1123	Mon, 02 Jan 2006 15:04:05 MST
+rfc3339	2006-01-02T15:04:05Z07:00
+rfc822	02 Jan 06 15:04 MST
+rsyslog	Jan _2 15:04:05
+syslog5424	2006-01-02T15:04:05.999999Z07:00
+unixdate	Mon Jan _2 15:04:05 MST 2006
+EOF
+
+tap go-dategrep --list-formats
+
+#################
+name "List named formats from the environment"
+
+stdout_is <<'EOF'
+app	2006/01/02 15:04:05
+EOF
+
+export GO_DATEGREP_FORMATS="app=2006/01/02 15:04:05"
+tap sh -c 'go-dategrep --list-formats | grep ^app'
+unset -v GO_DATEGREP_FORMATS
+
+#################
+
+done_testing
@@ -0,0 +1,57 @@
+#!tapsig
+
+cat > input <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+continuation
+2010-05-01T00:00:02Z line 3
+2010-05-01T00:00:03Z line 4
+EOF
+
+cat > other <<'EOF'
+2010-05-01T00:00:02Z other 1
+2010-05-01T00:00:05Z other 2
+EOF
+
+#################
+name "Count matching lines"
+
+stdout_is <<'EOF'
+3
+EOF
+
+tap go-dategrep --count --skip-dateless --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:04Z" input
+
+#################
+name "Count lines without date with --multiline"
+
+stdout_is <<'EOF'
+2
+EOF
+
+tap go-dategrep -c --multiline --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" input
+
+#################
+name "Count lines per file"
+
+stdout_is <<'EOF'
+input:2
+other:1
+-:0
+EOF
+
+tap sh -c 'go-dategrep -c --skip-dateless --format rfc3339 --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:05Z" input other - </dev/null'
+
+#################
+name "Count files without matching lines"
+
+rc_is 1
+stdout_is <<'EOF'
+other:0
+input:0
+EOF
+
+tap go-dategrep -c --skip-dateless --format rfc3339 --from "2010-05-01T00:00:10Z" --to "2010-05-01T00:00:20Z" other input
+
+#################
+done_testing
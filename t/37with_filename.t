@@ -0,0 +1,56 @@
+#!tapsig
+
+cat > input1 <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:02Z line 3
+EOF
+
+cat > input2 <<'EOF'
+2010-05-01T00:00:01Z line 2
+continuation
+EOF
+
+#################
+name "Print file names with multiple files"
+
+stdout_is <<'EOF'
+input1:2010-05-01T00:00:00Z line 1
+input2:2010-05-01T00:00:01Z line 2
+input2:continuation
+input1:2010-05-01T00:00:02Z line 3
+EOF
+
+tap go-dategrep --multiline --format rfc3339 --to "2010-05-01T00:00:03Z" input1 input2
+
+#################
+name "Print file name of a single file"
+
+stdout_is <<'EOF'
+input1:2010-05-01T00:00:00Z line 1
+input1:2010-05-01T00:00:02Z line 3
+EOF
+
+tap go-dategrep -H --format rfc3339 --to "2010-05-01T00:00:03Z" input1
+
+#################
+name "Print file name before byte offset"
+
+stdout_is <<'EOF'
+input1:28:2010-05-01T00:00:02Z line 3
+EOF
+
+tap go-dategrep --with-filename -b --format rfc3339 --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:03Z" input1
+
+#################
+name "Suppress file names"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:02Z line 3
+EOF
+
+tap go-dategrep --no-filename --skip-dateless --format rfc3339 --to "2010-05-01T00:00:03Z" input1 input2
+
+#################
+done_testing
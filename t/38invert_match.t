@@ -0,0 +1,65 @@
+#!tapsig
+
+cat > input <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+continuation 2
+2010-05-01T00:00:02Z line 3
+continuation 3
+2010-05-01T00:00:03Z line 4
+EOF
+
+#################
+name "Print lines outside of the range"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:03Z line 4
+EOF
+
+tap go-dategrep --invert-match --skip-dateless --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:03Z" input
+
+#################
+name "Print continuation lines of lines outside of the range"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:02Z line 3
+continuation 3
+2010-05-01T00:00:03Z line 4
+EOF
+
+tap go-dategrep -v --multiline --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" input
+
+#################
+name "Abort on lines without date"
+
+rc_is 2
+stdout_is <<'EOF'
+2010-05-01T00:00:00Z line 1
+EOF
+stderr_is <<'EOF'
+Aborting. Found line without date: continuation 2
+EOF
+
+tap go-dategrep -v --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" input
+
+#################
+name "Merge files outside of the range"
+
+cat > other <<'EOF'
+2010-05-01T00:00:00Z other 1
+2010-05-01T00:00:05Z other 2
+EOF
+
+stdout_is <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:00Z other 1
+2010-05-01T00:00:03Z line 4
+2010-05-01T00:00:05Z other 2
+EOF
+
+tap go-dategrep -h -v --skip-dateless --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:03Z" input other
+
+#################
+done_testing
@@ -0,0 +1,52 @@
+#!tapsig
+
+cat > input <<'EOF'
+2010-05-01T00:00:00Z line 1
+continuation
+2010-05-01T00:00:01Z line 2
+EOF
+
+#################
+name "Highlight timestamps"
+
+stdout_is <<EOF
+$(printf '\033[1;32m2010-05-01T00:00:00Z\033[0m line 1')
+continuation
+$(printf '\033[1;32m2010-05-01T00:00:01Z\033[0m line 2')
+EOF
+
+tap go-dategrep --color always --multiline --format rfc3339 --to "2010-05-01T00:00:02Z" input
+
+#################
+name "No colors if stdout isn't a terminal"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+EOF
+
+tap go-dategrep --skip-dateless --format rfc3339 --to "2010-05-01T00:00:02Z" input
+
+#################
+name "No colors with NO_COLOR"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:01Z line 2
+EOF
+
+export NO_COLOR=1
+tap go-dategrep --color always --skip-dateless --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" input
+unset -v NO_COLOR
+
+#################
+name "Unknown color mode"
+
+rc_is 2
+stderr_is <<'EOF'
+--color must be auto, always or never.
+EOF
+
+tap go-dategrep --color sometimes input
+
+#################
+done_testing
@@ -0,0 +1,61 @@
+#!tapsig
+
+cat > input <<'EOF'
+2010-05-01T00:00:00Z line 1
+no date
+2010-05-01T00:00:01Z line 2
+EOF
+
+#################
+name "Read options from --config"
+
+cat > config <<'EOF'
+# defaults for tests
+format = rfc3339
+
+skip-dateless
+EOF
+
+stdout_is <<'EOF'
+2010-05-01T00:00:01Z line 2
+EOF
+
+tap go-dategrep --config config --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" input
+
+#################
+name "Arguments override the configuration"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:00Z line 1
+no date
+2010-05-01T00:00:01Z line 2
+EOF
+
+tap go-dategrep --config=config --multiline --skip-dateless=false --to "2010-05-01T00:00:02Z" input
+
+#################
+name "Read the default configuration file"
+
+mkdir -p xdg/dtgrep
+cp config xdg/dtgrep/config
+
+stdout_is <<'EOF'
+2010-05-01T00:00:01Z line 2
+EOF
+
+tap sh -c 'XDG_CONFIG_HOME=$PWD/xdg go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" input'
+
+#################
+name "Unknown option in configuration"
+
+echo "colour = always" > bad
+
+rc_is 2
+stderr_is <<'EOF'
+Cannot read configuration: bad line 1: unknown option colour
+EOF
+
+tap go-dategrep --config bad input
+
+#################
+done_testing
@@ -0,0 +1,73 @@
+#!tapsig
+
+cat > input <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+EOF
+
+#################
+name "Print appended lines until --to"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:02Z line 3
+EOF
+
+tap sh -c '(sleep 1; echo "2010-05-01T00:00:02Z line 3" >> input; echo "2010-05-01T00:00:03Z line 4" >> input) & go-dategrep --follow --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:03Z" input'
+
+#################
+name "Follow needs a single file"
+
+rc_is 2
+stderr_is <<'EOF'
+--follow needs a single uncompressed file.
+EOF
+
+tap go-dategrep --follow --format rfc3339 input input
+
+#################
+name "Follow can't read stdin"
+
+rc_is 2
+stderr_is <<'EOF'
+--follow needs a single uncompressed file.
+EOF
+
+tap go-dategrep --follow --format rfc3339
+
+#################
+name "Save a checkpoint before following"
+
+cat > input2 <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:02Z line 3
+EOF
+
+stdout_is <<'EOF'
+2010-05-01T00:00:01Z line 2
+EOF
+
+tap go-dategrep --checkpoint cp --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" input2
+
+#################
+name "Follow from a checkpoint"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:02Z line 3
+2010-05-01T00:00:03Z line 4
+EOF
+
+tap sh -c '(sleep 1; echo "2010-05-01T00:00:03Z line 4" >> input2; echo "2010-05-01T00:00:04Z line 5" >> input2) & go-dategrep --follow --checkpoint cp --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:04Z" input2'
+
+#################
+name "Save the checkpoint while waiting for lines"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:05Z line 6
+EOF
+
+tap sh -c 'go-dategrep --follow --checkpoint cp --format rfc3339 --from "2010-05-01T00:00:01Z" input2 > /dev/null & sleep 1; kill $!; echo "2010-05-01T00:00:05Z line 6" >> input2; go-dategrep --checkpoint cp --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:10Z" input2'
+
+#################
+done_testing
@@ -0,0 +1,58 @@
+#!tapsig
+
+depends_on bgzip
+
+awk 'BEGIN { for (i = 0; i < 100000; i++) printf "2010-05-01T%02d:%02d:%02dZ line %d\n", i / 3600, i / 60 % 60, i % 60, i }' > input
+cp input indexed
+bgzip -i indexed
+
+#################
+name "Bisect bgzip file with index"
+
+stdout_is <<'EOF'
+2010-05-01T12:00:00Z line 43200
+2010-05-01T12:00:01Z line 43201
+EOF
+
+tap go-dategrep --format rfc3339 --from "2010-05-01T12:00:00Z" --to "2010-05-01T12:00:02Z" indexed.gz
+
+#################
+name "Read bgzip file without index"
+
+rm indexed.gz.gzi
+
+stdout_is <<'EOF'
+2010-05-01T12:00:00Z line 43200
+2010-05-01T12:00:01Z line 43201
+EOF
+
+tap go-dategrep --format rfc3339 --from "2010-05-01T12:00:00Z" --to "2010-05-01T12:00:02Z" indexed.gz
+
+#################
+name "Skip bgzip files with first and last date before the range"
+
+cat > unsorted <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:05Z line 2
+2010-05-01T00:00:07Z line 3
+2010-05-01T00:00:01Z line 4
+EOF
+bgzip -i unsorted
+
+rc_is 1
+stdout_is <<'EOF'
+EOF
+
+tap go-dategrep --format rfc3339 --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:06Z" unsorted.gz
+
+#################
+name "Search bgzip files with first and last date before the range"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:05Z line 2
+EOF
+
+tap go-dategrep --no-prefilter --format rfc3339 --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:06Z" unsorted.gz
+
+#################
+done_testing
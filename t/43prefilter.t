@@ -0,0 +1,63 @@
+#!tapsig
+
+cat > input <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:05Z line 2
+2010-05-01T00:00:07Z line 3
+2010-05-01T00:00:01Z line 4
+EOF
+
+#################
+name "Skip files with first and last date before the range"
+
+rc_is 1
+stdout_is <<'EOF'
+EOF
+
+tap go-dategrep --format rfc3339 --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:06Z" input
+
+#################
+name "Search files with first and last date before the range"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:05Z line 2
+EOF
+
+tap go-dategrep --no-prefilter --format rfc3339 --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:06Z" input
+
+#################
+name "Skip files with a cached index"
+
+rc_is 1
+stdout_is <<'EOF'
+EOF
+
+tap go-dategrep --cache-index cache --format rfc3339 --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:06Z" input
+
+#################
+name "Skip files named after days outside the range"
+
+printf '2023-11-01T10:00:00Z line 1\n' > app-2023-11-01.log
+printf '2023-11-02T10:00:00Z line 2\n' > app-2023-11-02.log
+printf 'not even a date\n' > app-2023-11-03.log
+
+stdout_is <<'EOF'
+app-2023-11-02.log:2023-11-02T10:00:00Z line 2
+EOF
+
+tap go-dategrep --filename-format 2006-01-02 --format rfc3339 --from "2023-11-02T00:00:00Z" --to "2023-11-03T00:00:00Z" app-2023-11-01.log app-2023-11-02.log app-2023-11-03.log
+
+#################
+name "Search files without date in their name"
+
+printf '2023-11-01T11:00:00Z line 3\n' > app.log
+
+stdout_is <<'EOF'
+app.log:2023-11-01T11:00:00Z line 3
+app-2023-11-02.log:2023-11-02T10:00:00Z line 2
+EOF
+
+tap go-dategrep --filename-format 2006-01-02 --format rfc3339 --from "2023-11-01T00:00:00Z" --to "2023-11-03T00:00:00Z" app.log app-2023-11-02.log
+
+#################
+done_testing
@@ -0,0 +1,23 @@
+#!tapsig
+
+depends_on mkfifo
+
+cat > input <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:02Z line 3
+EOF
+
+mkfifo fifo
+
+#################
+name "Read from a named pipe"
+
+stdout_is <<'EOF'
+2010-05-01T00:00:01Z line 2
+EOF
+
+tap sh -c 'cat input > fifo & go-dategrep --format rfc3339 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" fifo'
+
+#################
+done_testing
@@ -0,0 +1,21 @@
+#!tapsig
+
+depends_on xz
+
+#################
+name "Uncompress xz file on the fly"
+
+xz > input.xz <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:02Z line 3
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z line 2
+EOF
+
+tap go-dategrep --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:02Z" --format rfc3339 input.xz
+
+#################
+done_testing
@@ -0,0 +1,56 @@
+#!tapsig
+
+#################
+name "Limit must not be negative"
+
+rc_is 2
+
+stderr_is <<EOF
+--limit-memory must not be negative.
+EOF
+
+tap go-dategrep --limit-memory -1 --format rfc3339 input
+
+#################
+name "Spill sorted runs to disk"
+
+printf '2010-05-01T00:00:0%dZ line %d\n' 5 5 3 3 1 1 4 4 2 2 > input
+
+stdout_is <<EOF
+2010-05-01T00:00:02Z line 2
+2010-05-01T00:00:03Z line 3
+2010-05-01T00:00:04Z line 4
+EOF
+
+tap go-dategrep --pre-sort --limit-memory 30 --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:05Z" --format rfc3339 input
+
+#################
+name "Spill reversed lines to disk"
+
+cat > input1 <<EOF
+2010-05-01T00:00:00Z file 1 line 1
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+cat > input2 <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:05Z file 2 line 3
+EOF
+
+mkdir -p spill
+
+stdout_is <<EOF
+2010-05-01T00:00:05Z file 2 line 3
+2010-05-01T00:00:04Z file 1 line 3
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:01Z file 2 line 1
+0
+EOF
+
+tap sh -c 'TMPDIR=spill go-dategrep -h --reverse --limit-memory 40 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:06Z" --format rfc3339 input2 input1; ls spill | wc -l'
+
+#################
+done_testing
@@ -0,0 +1,32 @@
+#!tapsig
+
+depends_on gzip
+
+cat > input1 <<EOF
+2010-05-01T00:00:00Z file 1 line 1
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+cat > input2 <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:05Z file 2 line 3
+EOF
+
+#################
+name "Read files below directories"
+
+mkdir -p logs/old
+cp input1 logs/input1
+gzip -c input2 > logs/old/input2.gz
+
+stdout_is <<EOF
+logs/old/input2.gz:2010-05-01T00:00:01Z file 2 line 1
+logs/input1:2010-05-01T00:00:02Z file 1 line 2
+EOF
+
+tap go-dategrep -r --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:03Z" --format rfc3339 logs
+
+#################
+done_testing
@@ -0,0 +1,26 @@
+#!tapsig
+
+depends_on gzip
+
+# 100 lines with 29 bytes each
+i=0
+while [ $i -lt 100 ];do
+	printf '2010-05-01T00:00:%02dZ line %02d\n' $i $i
+	i=$((i + 1))
+done > input
+
+#################
+name "Slicing compressed files fails"
+
+gzip < input > input.gz
+
+rc_is 2
+
+stderr_is <<EOF
+Can't slice input.gz : file is not seekable
+EOF
+
+tap go-dategrep --from-percent 50 --format rfc3339 input.gz
+
+#################
+done_testing
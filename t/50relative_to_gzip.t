@@ -0,0 +1,21 @@
+#!tapsig
+
+depends_on gzip
+
+i=0
+while [ $i -lt 600 ];do
+	printf '2010-05-01T%02d:%02d:00Z line %d\n' $((i / 60)) $((i % 60)) $i
+	i=$((i + 1))
+done > input
+
+#################
+name "Relative to last date of compressed file"
+
+gzip < input > input.gz
+
+tail -n 120 input > stdout_plan
+
+tap go-dategrep --relative-to last --duration 2h --format rfc3339 input.gz
+
+#################
+done_testing
@@ -0,0 +1,48 @@
+#!tapsig
+
+cat > input1 <<EOF
+2010-05-01T00:00:00Z file 1 line 1
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+cat > input2 <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:05Z file 2 line 3
+EOF
+
+#################
+name "Expand quoted glob pattern"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:02Z file 1 line 2
+EOF
+
+tap go-dategrep -h --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:03Z" --format rfc3339 'input[12]'
+
+#################
+name "Glob pattern without matches"
+
+rc_is 2
+
+stderr_is <<EOF
+Cannot expand arguments: no files match nothing*.log
+EOF
+
+tap go-dategrep --format rfc3339 'nothing*.log'
+
+#################
+name "Open glob pattern with --no-glob"
+
+rc_is 2
+
+stderr_is <<EOF
+Cannot open input[12] : open input[12]: no such file or directory
+EOF
+
+tap go-dategrep --no-glob --format rfc3339 'input[12]'
+
+#################
+done_testing
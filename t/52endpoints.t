@@ -0,0 +1,47 @@
+#!tapsig
+
+cat > input1 <<EOF
+2010-05-01T00:00:00Z file 1 line 1
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+cat > input2 <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:05Z file 2 line 3
+EOF
+
+#################
+name "Print first and last line"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+tap go-dategrep -h --endpoints --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:05Z" --format rfc3339 input2 input1
+
+#################
+name "Print single matching line once"
+
+stdout_is <<EOF
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+tap go-dategrep -h --endpoints --from "2010-05-01T00:00:04Z" --to "2010-05-01T00:00:05Z" --format rfc3339 input2 input1
+
+#################
+name "Print first and last line of each file"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+tap go-dategrep -h --endpoints-per-file --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:05Z" --format rfc3339 input2 input1
+
+#################
+done_testing
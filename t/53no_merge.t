@@ -0,0 +1,61 @@
+#!tapsig
+
+cat > input1 <<EOF
+2010-05-01T00:00:00Z file 1 line 1
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+cat > input2 <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:05Z file 2 line 3
+EOF
+
+#################
+name "Print files one after another"
+
+stdout_is <<EOF
+input2:2010-05-01T00:00:01Z file 2 line 1
+input2:2010-05-01T00:00:03Z file 2 line 2
+input1:2010-05-01T00:00:02Z file 1 line 2
+input1:2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+tap go-dategrep --no-merge --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:05Z" --format rfc3339 input2 input1
+
+#################
+name "Print stdin in argument order"
+
+stdout_is <<EOF
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+2010-05-01T00:00:03Z stdin line
+EOF
+
+tap sh -c 'echo "2010-05-01T00:00:03Z stdin line" | go-dategrep -h --no-merge --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:05Z" --format rfc3339 input1 -'
+
+#################
+name "Print files ordered by their first matching line"
+
+stdout_is <<EOF
+input2:2010-05-01T00:00:01Z file 2 line 1
+input2:2010-05-01T00:00:03Z file 2 line 2
+input1:2010-05-01T00:00:02Z file 1 line 2
+input1:2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+tap go-dategrep --no-merge --sort-files --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:05Z" --format rfc3339 input1 input2
+
+#################
+name "Sorting files needs --no-merge"
+
+rc_is 2
+stderr_is <<EOF
+--sort-files can only be used with --no-merge.
+EOF
+
+tap go-dategrep --sort-files --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:05Z" --format rfc3339 input1 input2
+
+#################
+done_testing
@@ -0,0 +1,57 @@
+#!tapsig
+
+cat > input1 <<EOF
+2010-05-01T00:00:00Z file 1 line 1
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+cat > input2 <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:05Z file 2 line 3
+EOF
+
+#################
+name "Read file names from a file"
+
+printf 'input2\ninput1\n' > list
+
+stdout_is <<EOF
+input2:2010-05-01T00:00:01Z file 2 line 1
+input1:2010-05-01T00:00:02Z file 1 line 2
+EOF
+
+tap go-dategrep --files-from list --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:03Z" --format rfc3339
+
+#################
+name "Read file names from stdin"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:02Z file 1 line 2
+EOF
+
+tap sh -c 'printf "input1\n" | go-dategrep -h --files-from - --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:03Z" --format rfc3339 input2'
+
+#################
+name "Empty file list doesn't read stdin"
+
+rc_is 1
+stdout_is <<EOF
+EOF
+
+tap sh -c 'echo "2010-05-01T00:00:01Z stdin line" | go-dategrep --files-from /dev/null --format rfc3339 --to "2010-05-01T00:00:03Z"'
+
+#################
+name "Stdin can't be file list and input"
+
+rc_is 2
+stderr_is <<EOF
+Can't read lines from stdin with --files-from -.
+EOF
+
+tap sh -c 'echo input1 | go-dategrep --files-from - --format rfc3339 -'
+
+#################
+done_testing
@@ -0,0 +1,55 @@
+#!tapsig
+
+cat > input1 <<EOF
+2010-05-01T00:00:00Z file 1 line 1
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+cat > input2 <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:05Z file 2 line 3
+EOF
+
+#################
+name "Stop after max count"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:02Z file 1 line 2
+EOF
+
+tap go-dategrep -h --max-count 2 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:06Z" --format rfc3339 input2 input1
+
+#################
+name "Max count per file without merging"
+
+stdout_is <<EOF
+input2:2010-05-01T00:00:01Z file 2 line 1
+input1:2010-05-01T00:00:02Z file 1 line 2
+EOF
+
+tap go-dategrep --no-merge --max-count 1 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:06Z" --format rfc3339 input2 input1
+
+#################
+name "Count is capped by max count"
+
+stdout_is <<EOF
+input2:1
+input1:1
+EOF
+
+tap go-dategrep --count --max-count 2 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:06Z" --format rfc3339 input2 input1
+
+#################
+name "Merge limit in a single file"
+
+stdout_is <<EOF
+2010-05-01T00:00:02Z file 1 line 2
+EOF
+
+tap go-dategrep --merge-limit 1 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:06Z" --format rfc3339 input1
+
+#################
+done_testing
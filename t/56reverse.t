@@ -0,0 +1,73 @@
+#!tapsig
+
+cat > input1 <<EOF
+2010-05-01T00:00:00Z file 1 line 1
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+cat > input2 <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:05Z file 2 line 3
+EOF
+
+#################
+name "Print newest lines first"
+
+stdout_is <<EOF
+2010-05-01T00:00:04Z file 1 line 3
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:01Z file 2 line 1
+EOF
+
+tap go-dategrep -h --reverse --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:05Z" --format rfc3339 input2 input1
+
+#################
+name "Print newest lines up to max count"
+
+stdout_is <<EOF
+2010-05-01T00:00:05Z file 2 line 3
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+tap go-dategrep -h --reverse --max-count 2 --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:06Z" --format rfc3339 input2 input1
+
+#################
+name "Separators don't count toward max count"
+
+stdout_is <<EOF
+2010-05-01T00:00:04Z file 1 line 3
+--
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:02Z file 1 line 2
+EOF
+
+tap go-dategrep -h --reverse --max-count 3 --context-before 1s --context-after 1s --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:04Z" --format rfc3339 input2 input1
+
+#################
+name "Keep continuation lines in reverse"
+
+printf '2010-05-01T00:00:01Z first\n  more\n2010-05-01T00:00:02Z second\n' > multi
+
+stdout_is <<EOF
+2010-05-01T00:00:02Z second
+2010-05-01T00:00:01Z first
+  more
+EOF
+
+tap go-dategrep --multiline --reverse --from "2010-05-01T00:00:01Z" --to "2010-05-01T00:00:05Z" --format rfc3339 multi
+
+#################
+name "Reverse can't be used with follow"
+
+rc_is 2
+stderr_is <<EOF
+--reverse can't be used with --follow.
+EOF
+
+tap go-dategrep --reverse --follow --format rfc3339 input1
+
+#################
+done_testing
@@ -0,0 +1,52 @@
+#!tapsig
+
+cat > input1 <<EOF
+2010-05-01T00:00:00Z file 1 line 1
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+cat > input2 <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:05Z file 2 line 3
+EOF
+
+#################
+name "Print context lines around the range"
+
+stdout_is <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+--
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:03Z file 2 line 2
+--
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+tap go-dategrep -h --context-before 1s --context-after 1s --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:04Z" --format rfc3339 input2 input1
+
+#################
+name "Print context lines after the range only"
+
+stdout_is <<EOF
+2010-05-01T00:00:02Z file 1 line 2
+--
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+tap go-dategrep -h --context-after 2s --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:03Z" --format rfc3339 input2 input1
+
+#################
+name "Context lines can't be counted"
+
+rc_is 2
+stderr_is <<EOF
+--context-before and --context-after can't be used with --count or --endpoints.
+EOF
+
+tap go-dategrep --count --context-after 2s --format rfc3339 input1
+
+#################
+done_testing
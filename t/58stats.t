@@ -0,0 +1,91 @@
+#!tapsig
+
+cat > input1 <<EOF
+2010-05-01T00:00:00Z file 1 line 1
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:04Z file 1 line 3
+EOF
+
+cat > input2 <<EOF
+2010-05-01T00:00:01Z file 2 line 1
+2010-05-01T00:00:03Z file 2 line 2
+2010-05-01T00:00:05Z file 2 line 3
+EOF
+
+#################
+name "Print statistics to stderr"
+
+stdout_is <<EOF
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:03Z file 2 line 2
+EOF
+stderr_is <<EOF
+Range: 2010-05-01T00:00:02Z - 2010-05-01T00:00:04Z
+Matched: 2010-05-01T00:00:02Z - 2010-05-01T00:00:03Z
+Span: 1s
+Lines: 2
+input2: 1
+input1: 1
+EOF
+
+tap go-dategrep -h --stats --location UTC --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:04Z" --format rfc3339 input2 input1
+
+#################
+name "Print the span of the statistics in words"
+
+stdout_is <<EOF
+2010-05-01T00:00:02Z file 1 line 2
+2010-05-01T00:00:03Z file 2 line 2
+EOF
+stderr_is <<EOF
+Range: 2010-05-01T00:00:02Z - 2010-05-01T00:00:04Z
+Matched: 2010-05-01T00:00:02Z - 2010-05-01T00:00:03Z
+Span: 1 second
+Lines: 2
+input2: 1
+input1: 1
+EOF
+
+tap go-dategrep -h --stats --human --location UTC --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:04Z" --format rfc3339 input2 input1
+
+#################
+name "Print statistics without matches"
+
+rc_is 1
+stdout_is <<EOF
+EOF
+stderr_is <<EOF
+Range: 2011-05-01T00:00:00Z - 2012-01-01T00:00:00Z
+Matched: nothing
+Lines: 0
+input1: 0
+EOF
+
+tap go-dategrep --stats --now "2012-01-01T00:00:00Z" --location UTC --from "2011-05-01T00:00:00Z" --format rfc3339 input1
+
+#################
+name "Print statistics of the newest lines"
+
+cat > input <<EOF
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:01Z line 2
+2010-05-01T00:00:02Z line 3
+2010-05-01T00:00:03Z line 4
+EOF
+
+stdout_is <<EOF
+2010-05-01T00:00:03Z line 4
+2010-05-01T00:00:02Z line 3
+EOF
+stderr_is <<EOF
+Range: 2010-05-01T00:00:00Z - 2010-05-01T00:00:04Z
+Matched: 2010-05-01T00:00:02Z - 2010-05-01T00:00:03Z
+Span: 1s
+Lines: 2
+input: 2
+EOF
+
+tap go-dategrep --stats --reverse --max-count 2 --location UTC --from "2010-05-01T00:00:00Z" --to "2010-05-01T00:00:04Z" --format rfc3339 input
+
+#################
+done_testing
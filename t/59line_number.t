@@ -0,0 +1,57 @@
+#!tapsig
+
+cat > input1 <<'EOF'
+2010-05-01T00:00:00Z line 1
+2010-05-01T00:00:02Z line 3
+EOF
+
+cat > input2 <<'EOF'
+2010-05-01T00:00:01Z line 2
+continuation
+EOF
+
+#################
+name "Print line numbers after file names"
+
+stdout_is <<'EOF'
+input1:1:2010-05-01T00:00:00Z line 1
+input2:1:2010-05-01T00:00:01Z line 2
+input2:2:continuation
+input1:2:2010-05-01T00:00:02Z line 3
+EOF
+
+tap go-dategrep -n --multiline --format rfc3339 --to "2010-05-01T00:00:03Z" input1 input2
+
+#################
+name "Print line numbers before byte offsets"
+
+stdout_is <<'EOF'
+2:28:2010-05-01T00:00:02Z line 3
+EOF
+
+tap go-dategrep -n -b --format rfc3339 --from "2010-05-01T00:00:02Z" --to "2010-05-01T00:00:03Z" input1
+
+#################
+name "Print line numbers of a long file"
+
+seq 0 999 | while read n; do printf '2010-05-01T00:%02d:%02dZ line\n' $((n / 60)) $((n % 60)); done > long
+
+stdout_is <<'EOF'
+901:2010-05-01T00:15:00Z line
+902:2010-05-01T00:15:01Z line
+EOF
+
+tap go-dategrep -n --format rfc3339 --from "2010-05-01T00:15:00Z" --to "2010-05-01T00:15:02Z" long
+
+#################
+name "Line numbers can't be used with --from-percent"
+
+rc_is 2
+stderr_is <<'EOF'
+--from-percent and --to-percent can't be used with --line-number.
+EOF
+
+tap go-dategrep -n --from-percent 50 long
+
+#################
+done_testing
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// isURL reports whether filename is an HTTP or HTTPS URL to read lines
+// from.
+func isURL(filename string) bool {
+	return strings.HasPrefix(filename, "http://") || strings.HasPrefix(filename, "https://")
+}
+
+// encodings maps values of the Content-Encoding header to their
+// compression. The http package already decodes gzip if it asked for it.
+var encodings = map[string]compression{
+	"gzip":       gzipCompression,
+	"x-gzip":     gzipCompression,
+	"bzip2":      bzip2Compression,
+	"xz":         xzCompression,
+	"compress":   lzwCompression,
+	"x-compress": lzwCompression,
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// openURL fetches rawURL and returns its body, decompressed according to
+// the Content-Encoding header or else the extension of the path.
+func openURL(rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.New("HTTP status " + resp.Status)
+	}
+
+	c, ok := compressions[path.Ext(u.Path)]
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		c, ok = encodings[strings.ToLower(encoding)]
+		if !ok {
+			resp.Body.Close()
+			return nil, errors.New("unknown Content-Encoding " + encoding)
+		}
+	} else if resp.Uncompressed {
+		ok = false
+	}
+	if !ok {
+		return resp.Body, nil
+	}
+	r, err := c.newReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return readCloser{r, resp.Body}, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenURL(t *testing.T) {
+	const lines = "2010-05-01T00:00:00Z line 1\n2010-05-01T00:00:01Z line 2\n"
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write([]byte(lines))
+	w.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/plain.log":
+			w.Write([]byte(lines))
+		case "/old.log.gz":
+			w.Write(compressed.Bytes())
+		case "/encoded.log":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes())
+		case "/encoded.log.gz":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes())
+		case "/unknown.log":
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte(lines))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, name := range []string{"/plain.log", "/old.log.gz", "/encoded.log", "/encoded.log.gz"} {
+		r, err := openURL(server.URL + name)
+		if err != nil {
+			t.Errorf("openURL(%q) failed: %v", name, err)
+			continue
+		}
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil || string(data) != lines {
+			t.Errorf("Reading %q returned %q, %v", name, data, err)
+		}
+	}
+
+	if _, err := openURL(server.URL + "/missing.log"); err == nil || err.Error() != "HTTP status 404 Not Found" {
+		t.Errorf("openURL of a missing page returned %v", err)
+	}
+	if _, err := openURL(server.URL + "/unknown.log"); err == nil {
+		t.Error("openURL with unknown Content-Encoding succeeded")
+	}
+}
+
+func TestFileTypeURL(t *testing.T) {
+	if fileType("https://example.com/app.log.gz") != "url" {
+		t.Error("fileType doesn't recognize URLs")
+	}
+	files, err := expandGlobs([]string{"http://example.com/app.log?date=2010-05-01"})
+	if err != nil || len(files) != 1 {
+		t.Errorf("expandGlobs of a URL returned %v, %v", files, err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// webhookWorkers is the number of requests sent at the same time.
+const webhookWorkers = 4
+
+// webhookRetries is the number of attempts to send a line.
+const webhookRetries = 3
+
+var webhookRetryDelay = time.Second
+
+// webhook posts lines as JSON objects to a URL. Lines that can't be sent
+// are reported and dropped.
+type webhook struct {
+	url     string
+	client  *http.Client
+	records chan jsonRecord
+	wg      sync.WaitGroup
+}
+
+func newWebhook(url string) *webhook {
+	w := &webhook{
+		url:     url,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		records: make(chan jsonRecord, webhookWorkers),
+	}
+	for n := 0; n < webhookWorkers; n++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for r := range w.records {
+				if err := w.post(r); err != nil {
+					log.Println("Warning: Can't send line to webhook:", err)
+				}
+			}
+		}()
+	}
+	return w
+}
+
+func (w *webhook) send(r jsonRecord) {
+	w.records <- r
+}
+
+// close waits until all lines are sent.
+func (w *webhook) close() {
+	close(w.records)
+	w.wg.Wait()
+}
+
+func (w *webhook) post(r jsonRecord) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		err = w.postOnce(body)
+		if err == nil || attempt == webhookRetries {
+			return err
+		}
+		time.Sleep(webhookRetryDelay)
+	}
+}
+
+func (w *webhook) postOnce(body []byte) error {
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New(w.url + " returned " + resp.Status)
+	}
+	return nil
+}
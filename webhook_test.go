@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	var mu sync.Mutex
+	var messages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record jsonRecord
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+			t.Error("Can't decode payload:", err)
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Error("Wrong content type", r.Header.Get("Content-Type"))
+		}
+		mu.Lock()
+		messages = append(messages, record.File+" "+record.Time+" "+record.Message)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	w := newWebhook(server.URL)
+	w.send(jsonRecord{File: "a.log", Time: "2010-05-01T00:00:00Z", Message: "line 1"})
+	w.send(jsonRecord{File: "b.log", Time: "2010-05-01T00:00:01Z", Message: "line 2"})
+	w.send(jsonRecord{File: "b.log", Message: "foo"})
+	w.close()
+
+	sort.Strings(messages)
+	expected := []string{
+		"a.log 2010-05-01T00:00:00Z line 1",
+		"b.log  foo",
+		"b.log 2010-05-01T00:00:01Z line 2",
+	}
+	if len(messages) != len(expected) {
+		t.Fatal("Webhook received", messages)
+	}
+	for k, v := range expected {
+		if messages[k] != v {
+			t.Errorf("Webhook received %q, expected %q", messages[k], v)
+		}
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	defer func(saved time.Duration) { webhookRetryDelay = saved }(webhookRetryDelay)
+	webhookRetryDelay = 0
+	var mu sync.Mutex
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts < webhookRetries {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	w := newWebhook(server.URL)
+	if err := w.post(jsonRecord{Message: "line 1"}); err != nil {
+		t.Error("Posting with retries failed:", err)
+	}
+	w.close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	w = newWebhook(failing.URL)
+	if err := w.post(jsonRecord{Message: "line 2"}); err == nil {
+		t.Error("Posting to failing server succeeded")
+	}
+	w.close()
+}
+
+func TestWebhookPrintedLines(t *testing.T) {
+	var mu sync.Mutex
+	var messages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record jsonRecord
+		json.NewDecoder(r.Body).Decode(&record)
+		mu.Lock()
+		messages = append(messages, record.Message)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	hook = newWebhook(server.URL)
+	defer func() { hook = nil }()
+	defer func(saved int) { emitted = saved }(emitted)
+
+	var out bytes.Buffer
+	i := &Iterator{filename: "input", out: &out}
+	dt := time.Date(2010, 5, 1, 0, 0, 0, 0, time.UTC)
+	i.write("counted", "counted", dt, false, Options{count: true})
+	i.write("endpoint", "endpoint", dt, false, Options{endpoints: true})
+	i.write(`{"message":"printed"}`, "printed", dt, false, Options{})
+	i.write("reversed", "reversed", dt, false, Options{reverse: true})
+	i.write("marker", "", time.Time{}, true, Options{})
+	reversed.flush()
+	hook.close()
+
+	sort.Strings(messages)
+	if strings.Join(messages, " ") != "printed reversed" {
+		t.Errorf("Webhook received %q", messages)
+	}
+}